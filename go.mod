@@ -10,4 +10,4 @@ require (
 
 require golang.org/x/crypto v0.31.0
 
-require github.com/golang-jwt/jwt/v5 v5.2.1 // indirect
+require github.com/golang-jwt/jwt/v5 v5.2.1
@@ -9,54 +9,114 @@ import (
 	"github.com/kwekkwekpatu/chirpy/internal/util"
 )
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+// Claims are the claims of an access token. SessionID names the refresh
+// token family the access token was issued for, if any.
+type Claims struct {
+	jwt.RegisteredClaims
+	SessionID string `json:"sid,omitempty"`
+}
+
+func (keyring *Keyring) MakeJWT(userID uuid.UUID, expiresIn time.Duration) (string, error) {
+	return keyring.MakeSessionJWT(userID, uuid.Nil, expiresIn)
+}
+
+// MakeSessionJWT makes an access token that remembers the session it was
+// issued for. A nil sessionID is left out of the token.
+func (keyring *Keyring) MakeSessionJWT(userID uuid.UUID, sessionID uuid.UUID, expiresIn time.Duration) (string, error) {
 	util.InfoLogger.Printf("Generating new JWT for %s", userID.String())
-	claims := jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
-		Subject:   userID.String(),
+	keyID, key, err := keyring.signingKey()
+	if err != nil {
+		return "", err
+	}
+
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if sessionID != uuid.Nil {
+		claims.SessionID = sessionID.String()
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = keyID
 
-	signedJWT, err := token.SignedString([]byte(tokenSecret))
+	signedJWT, err := token.SignedString(key)
 
 	util.InfoLogger.Printf("Succesfully generated JWT for %s", userID.String())
 	return signedJWT, err
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+func (keyring *Keyring) ValidateJWT(tokenString string) (uuid.UUID, error) {
+	userID, _, err := keyring.ValidateJWTWithExpiry(tokenString)
+	return userID, err
+}
+
+// ValidateJWTWithExpiry validates like ValidateJWT and also returns when the
+// token expires, for connections that outlive a single request.
+func (keyring *Keyring) ValidateJWTWithExpiry(tokenString string) (uuid.UUID, time.Time, error) {
+	userID, claims, err := keyring.parseJWT(tokenString)
+	if err != nil {
+		return uuid.UUID{}, time.Time{}, err
+	}
+
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return uuid.UUID{}, time.Time{}, fmt.Errorf("token has no expiration time")
+	}
+	return userID, expiresAt.Time, nil
+}
+
+// ValidateSessionJWT validates like ValidateJWT and also returns the session
+// the token was issued for. Tokens made without a session return an invalid
+// NullUUID.
+func (keyring *Keyring) ValidateSessionJWT(tokenString string) (uuid.UUID, uuid.NullUUID, error) {
+	userID, claims, err := keyring.parseJWT(tokenString)
+	if err != nil {
+		return uuid.UUID{}, uuid.NullUUID{}, err
+	}
+
+	if claims.SessionID == "" {
+		return userID, uuid.NullUUID{}, nil
+	}
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return uuid.UUID{}, uuid.NullUUID{}, fmt.Errorf("invalid session id: %w", err)
+	}
+	return userID, uuid.NullUUID{UUID: sessionID, Valid: true}, nil
+}
+
+func (keyring *Keyring) parseJWT(tokenString string) (uuid.UUID, *Claims, error) {
 	util.InfoLogger.Printf("Validating JWT")
 
-	token, err := jwt.ParseWithClaims(
-		tokenString,
-		&jwt.RegisteredClaims{},
-		func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(tokenSecret), nil
-		})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keyring.verificationKey)
 	if err != nil {
 		util.ErrorLogger.Printf("Failed to validate JWT with error: %s", err.Error())
-		return uuid.UUID{}, err
+		return uuid.UUID{}, nil, err
 	}
 
 	if !token.Valid {
-		return uuid.UUID{}, fmt.Errorf("invalid token")
+		return uuid.UUID{}, nil, fmt.Errorf("invalid token")
 	}
 
-	claims, ok := token.Claims.(*jwt.RegisteredClaims)
+	claims, ok := token.Claims.(*Claims)
 	if !ok {
-		return uuid.UUID{}, fmt.Errorf("invalid claims type")
+		return uuid.UUID{}, nil, fmt.Errorf("invalid claims type")
 	}
 
 	userIDString, err := claims.GetSubject()
 	if err != nil {
 		util.ErrorLogger.Printf("Failed to retriev UserID with error: %s", err.Error())
-		return uuid.UUID{}, err
+		return uuid.UUID{}, nil, err
+	}
+
+	userID, err := uuid.Parse(userIDString)
+	if err != nil {
+		return uuid.UUID{}, nil, err
 	}
 
 	util.InfoLogger.Printf("Succesfully validated JWT for %s", userIDString)
-	return uuid.Parse(userIDString)
+	return userID, claims, nil
 }
@@ -4,21 +4,31 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/kwekkwekpatu/chirpy/internal/auth"
 )
 
+func newKeyring(t *testing.T) *auth.Keyring {
+	t.Helper()
+	keyring, err := auth.GenerateKeyring()
+	if err != nil {
+		t.Fatalf("Failed to generate keyring: %v", err)
+	}
+	return keyring
+}
+
 func TestValidateJWT(t *testing.T) {
 	// Setup
-	secret := "test_secret"
+	keyring := newKeyring(t)
 	userID := uuid.New()
 
 	// Test 1: Valid token
-	token, err := auth.MakeJWT(userID, secret, time.Hour)
+	token, err := keyring.MakeJWT(userID, time.Hour)
 	if err != nil {
 		t.Fatalf("Failed to create test token: %v", err)
 	}
-	gotID, err := auth.ValidateJWT(token, secret)
+	gotID, err := keyring.ValidateJWT(token)
 	if err != nil {
 		t.Errorf("ValidateJWT failed with valid token: %v", err)
 	}
@@ -26,57 +36,61 @@ func TestValidateJWT(t *testing.T) {
 		t.Errorf("Got wrong user ID. Want %v, got %v", userID, gotID)
 	}
 
-	// Test 2: Wrong secret
-	_, err = auth.ValidateJWT(token, "wrong_secret")
+	// Test 2: Wrong keyring
+	_, err = newKeyring(t).ValidateJWT(token)
 	if err == nil {
-		t.Error("ValidateJWT succeeded with wrong secret")
+		t.Error("ValidateJWT succeeded with a key from another keyring")
 	}
 
 	// Test 3: Expired token
-	expiredToken, _ := auth.MakeJWT(userID, secret, -time.Hour) // negative duration makes it already expired
-	_, err = auth.ValidateJWT(expiredToken, secret)
+	expiredToken, _ := keyring.MakeJWT(userID, -time.Hour) // negative duration makes it already expired
+	_, err = keyring.ValidateJWT(expiredToken)
 	if err == nil {
 		t.Error("ValidateJWT succeeded with expired token")
 	}
 
 	// Test 4: Malformed token
-	_, err = auth.ValidateJWT("not.a.token", secret)
+	_, err = keyring.ValidateJWT("not.a.token")
 	if err == nil {
 		t.Error("ValidateJWT succeeded with malformed token")
 	}
 
-	// Test 5: Empty secret
-	_, err = auth.ValidateJWT(token, "")
+	// Test 5: Symmetric token
+	hmacToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   userID.String(),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).SignedString([]byte("test_secret"))
+	_, err = keyring.ValidateJWT(hmacToken)
 	if err == nil {
-		t.Error("ValidateJWT accepted token with empty secret when it should have failed")
+		t.Error("ValidateJWT accepted an HS256 token when it should have failed")
 	}
 
 	// Test 6: Empty UUID
-	emptyUUIDToken, _ := auth.MakeJWT(uuid.UUID{}, secret, -time.Hour)
-	_, err = auth.ValidateJWT(emptyUUIDToken, secret)
+	emptyUUIDToken, _ := keyring.MakeJWT(uuid.UUID{}, -time.Hour)
+	_, err = keyring.ValidateJWT(emptyUUIDToken)
 	if err == nil {
 		t.Error("ValidateJWT succeeded with empty UUID in token")
 	}
 
 	// Test 7: Check if token expires correctly
 	// Create token that expires in 1 second
-	expiringToken, _ := auth.MakeJWT(userID, secret, time.Second)
+	expiringToken, _ := keyring.MakeJWT(userID, time.Second)
 	// First validation should pass
-	_, err = auth.ValidateJWT(expiringToken, secret)
+	_, err = keyring.ValidateJWT(expiringToken)
 	if err != nil {
 		t.Error("Token should be valid initially")
 	}
 	// Wait 2 seconds
 	time.Sleep(2 * time.Second)
 	// Now validation should fail
-	_, err = auth.ValidateJWT(expiringToken, secret)
+	_, err = keyring.ValidateJWT(expiringToken)
 	if err == nil {
 		t.Error("Token should have expired")
 	}
 }
 
 func TestValidateJWTFormat(t *testing.T) {
-	secret := "test_secret"
+	keyring := newKeyring(t)
 
 	// Test different malformed tokens
 	malformedTokens := []struct {
@@ -107,10 +121,52 @@ func TestValidateJWTFormat(t *testing.T) {
 
 	for _, tc := range malformedTokens {
 		t.Run(tc.name, func(t *testing.T) {
-			_, err := auth.ValidateJWT(tc.token, secret)
+			_, err := keyring.ValidateJWT(tc.token)
 			if err == nil {
 				t.Errorf("ValidateJWT accepted malformed token '%s' when it should have failed", tc.token)
 			}
 		})
 	}
 }
+
+func TestValidateSessionJWT(t *testing.T) {
+	keyring := newKeyring(t)
+	userID := uuid.New()
+	sessionID := uuid.New()
+
+	tests := []struct {
+		name      string
+		sessionID uuid.UUID
+		want      uuid.NullUUID
+	}{
+		{
+			name:      "with session",
+			sessionID: sessionID,
+			want:      uuid.NullUUID{UUID: sessionID, Valid: true},
+		},
+		{
+			name:      "without session",
+			sessionID: uuid.Nil,
+			want:      uuid.NullUUID{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := keyring.MakeSessionJWT(userID, tc.sessionID, time.Hour)
+			if err != nil {
+				t.Fatalf("Failed to create test token: %v", err)
+			}
+			gotUserID, gotSessionID, err := keyring.ValidateSessionJWT(token)
+			if err != nil {
+				t.Fatalf("ValidateSessionJWT failed with valid token: %v", err)
+			}
+			if gotUserID != userID {
+				t.Errorf("Got wrong user ID. Want %v, got %v", userID, gotUserID)
+			}
+			if gotSessionID != tc.want {
+				t.Errorf("Got wrong session ID. Want %v, got %v", tc.want, gotSessionID)
+			}
+		})
+	}
+}
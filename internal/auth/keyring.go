@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Keyring holds the Ed25519 keys for access tokens. One private key signs new
+// tokens and every key verifies them, so keys rotate without downtime:
+// publish the next key first, switch signing to it, and drop the old key once
+// its last tokens have expired.
+type Keyring struct {
+	mu        sync.RWMutex
+	signingID string
+	signing   ed25519.PrivateKey
+	verifying map[string]ed25519.PublicKey
+}
+
+func NewKeyring() *Keyring {
+	return &Keyring{verifying: map[string]ed25519.PublicKey{}}
+}
+
+// GenerateKeyring makes a keyring with a fresh signing key. Tokens it signs
+// stop validating when the keyring is gone, so it is for development only.
+func GenerateKeyring() (*Keyring, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	keyring := NewKeyring()
+	keyring.SetSigningKey(Thumbprint(privateKey.Public().(ed25519.PublicKey)), privateKey)
+	return keyring, nil
+}
+
+// LoadKeyring reads every .pem file in dir. The file name without .pem is the
+// key ID. Private keys are PKCS #8 and public keys PKIX, as written by
+//
+//	openssl genpkey -algorithm ed25519 -out <id>.pem
+//	openssl pkey -in private.pem -pubout -out <id>.pem
+//
+// signingID picks the key that signs; it must be a private key. Every other
+// key only verifies.
+func LoadKeyring(dir, signingID string) (*Keyring, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keyring := NewKeyring()
+	for _, path := range paths {
+		id := strings.TrimSuffix(filepath.Base(path), ".pem")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%s: no PEM data", path)
+		}
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err := jwt.ParseEdPrivateKeyFromPEM(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			privateKey := key.(ed25519.PrivateKey)
+			if id == signingID {
+				keyring.SetSigningKey(id, privateKey)
+			} else {
+				keyring.AddVerificationKey(id, privateKey.Public().(ed25519.PublicKey))
+			}
+		case "PUBLIC KEY":
+			key, err := jwt.ParseEdPublicKeyFromPEM(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			keyring.AddVerificationKey(id, key.(ed25519.PublicKey))
+		default:
+			return nil, fmt.Errorf("%s: unexpected PEM block %q", path, block.Type)
+		}
+	}
+
+	if keyring.signing == nil {
+		return nil, fmt.Errorf("no private key %q in %s", signingID, dir)
+	}
+	return keyring, nil
+}
+
+// SetSigningKey makes key sign new tokens. It also verifies them.
+func (keyring *Keyring) SetSigningKey(id string, key ed25519.PrivateKey) {
+	keyring.mu.Lock()
+	defer keyring.mu.Unlock()
+
+	keyring.signingID = id
+	keyring.signing = key
+	keyring.verifying[id] = key.Public().(ed25519.PublicKey)
+}
+
+// AddVerificationKey accepts tokens signed by key under id.
+func (keyring *Keyring) AddVerificationKey(id string, key ed25519.PublicKey) {
+	keyring.mu.Lock()
+	defer keyring.mu.Unlock()
+
+	keyring.verifying[id] = key
+}
+
+func (keyring *Keyring) signingKey() (string, ed25519.PrivateKey, error) {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+
+	if keyring.signing == nil {
+		return "", nil, fmt.Errorf("keyring has no signing key")
+	}
+	return keyring.signingID, keyring.signing, nil
+}
+
+// verificationKey is a jwt.Keyfunc that only accepts EdDSA tokens whose kid
+// is in the keyring.
+func (keyring *Keyring) verificationKey(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodEd25519); !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	id, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, fmt.Errorf("token has no key id")
+	}
+
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+
+	key, ok := keyring.verifying[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", id)
+	}
+	return key, nil
+}
+
+// JWK is a public key in JSON Web Key form (RFC 8037).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS lists the verification keys, ordered by key ID.
+func (keyring *Keyring) JWKS() JWKSet {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+	for id, key := range keyring.verifying {
+		set.Keys = append(set.Keys, JWK{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(key),
+			KeyID:     id,
+			Use:       "sig",
+			Algorithm: "EdDSA",
+		})
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+	return set
+}
+
+// Thumbprint is the RFC 7638 thumbprint of key, a stable ID for keys that
+// were not given one.
+func Thumbprint(key ed25519.PublicKey) string {
+	// The members are required to be in lexicographic order with no spaces.
+	canonical := fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":"%s"}`, base64.RawURLEncoding.EncodeToString(key))
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
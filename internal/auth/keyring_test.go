@@ -0,0 +1,169 @@
+package auth_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/auth"
+)
+
+func generateKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return key
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	userID := uuid.New()
+	oldKey := generateKey(t)
+	newKey := generateKey(t)
+
+	keyring := auth.NewKeyring()
+	keyring.SetSigningKey("old", oldKey)
+	oldToken, err := keyring.MakeJWT(userID, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	keyring.SetSigningKey("new", newKey)
+	newToken, err := keyring.MakeJWT(userID, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	verifier := auth.NewKeyring()
+	verifier.AddVerificationKey("new", newKey.Public().(ed25519.PublicKey))
+
+	tests := []struct {
+		name    string
+		keyring *auth.Keyring
+		token   string
+		wantErr bool
+	}{
+		{name: "old token after rotation", keyring: keyring, token: oldToken},
+		{name: "new token after rotation", keyring: keyring, token: newToken},
+		{name: "verification key only", keyring: verifier, token: newToken},
+		{name: "unknown key id", keyring: verifier, token: oldToken, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gotID, err := tc.keyring.ValidateJWT(tc.token)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("ValidateJWT succeeded when it should have failed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateJWT failed: %v", err)
+			}
+			if gotID != userID {
+				t.Errorf("Got wrong user ID. Want %v, got %v", userID, gotID)
+			}
+		})
+	}
+
+	_, err = verifier.MakeJWT(userID, time.Hour)
+	if err == nil {
+		t.Error("MakeJWT succeeded without a signing key")
+	}
+}
+
+func TestKeyringJWKS(t *testing.T) {
+	signingKey := generateKey(t)
+	retiredKey := generateKey(t)
+
+	keyring := auth.NewKeyring()
+	keyring.SetSigningKey("b", signingKey)
+	keyring.AddVerificationKey("a", retiredKey.Public().(ed25519.PublicKey))
+
+	set := keyring.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("Got %d keys, want 2", len(set.Keys))
+	}
+
+	wantKeys := []ed25519.PrivateKey{retiredKey, signingKey}
+	for i, key := range set.Keys {
+		wantID := []string{"a", "b"}[i]
+		if key.KeyID != wantID {
+			t.Errorf("Key %d has ID %q, want %q", i, key.KeyID, wantID)
+		}
+		if key.KeyType != "OKP" || key.Curve != "Ed25519" || key.Algorithm != "EdDSA" || key.Use != "sig" {
+			t.Errorf("Key %q has unexpected parameters: %+v", key.KeyID, key)
+		}
+		wantX := base64.RawURLEncoding.EncodeToString(wantKeys[i].Public().(ed25519.PublicKey))
+		if key.X != wantX {
+			t.Errorf("Key %q has x %q, want %q", key.KeyID, key.X, wantX)
+		}
+	}
+}
+
+func TestThumbprint(t *testing.T) {
+	// The Ed25519 example from RFC 8037, appendix A.3.
+	x, _ := base64.RawURLEncoding.DecodeString("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+	want := "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k"
+
+	if got := auth.Thumbprint(ed25519.PublicKey(x)); got != want {
+		t.Errorf("Got thumbprint %q, want %q", got, want)
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	dir := t.TempDir()
+	signingKey := generateKey(t)
+	nextKey := generateKey(t)
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(signingKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal private key: %v", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(nextKey.Public())
+	if err != nil {
+		t.Fatalf("Failed to marshal public key: %v", err)
+	}
+	writePEM(t, filepath.Join(dir, "current.pem"), "PRIVATE KEY", privateDER)
+	writePEM(t, filepath.Join(dir, "next.pem"), "PUBLIC KEY", publicDER)
+
+	_, err = auth.LoadKeyring(dir, "next")
+	if err == nil {
+		t.Error("LoadKeyring accepted a public key for signing")
+	}
+
+	keyring, err := auth.LoadKeyring(dir, "current")
+	if err != nil {
+		t.Fatalf("LoadKeyring failed: %v", err)
+	}
+	if got := len(keyring.JWKS().Keys); got != 2 {
+		t.Errorf("Got %d keys, want 2", got)
+	}
+
+	token, err := keyring.MakeJWT(uuid.New(), time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	verifier := auth.NewKeyring()
+	verifier.AddVerificationKey("current", signingKey.Public().(ed25519.PublicKey))
+	if _, err := verifier.ValidateJWT(token); err != nil {
+		t.Errorf("Token was not signed with the current key: %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+// Package authz describes what an authenticated user is allowed to do.
+// Permissions are strings of colon separated segments such as
+// "chirps:delete:any". A trailing "*" segment grants everything below it,
+// so "chirps:*" covers "chirps:delete:any" and "*" covers every permission.
+package authz
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type Permission string
+
+const (
+	DeleteAnyChirp        Permission = "chirps:delete:any"
+	ReadDeletedChirps     Permission = "chirps:read:deleted"
+	RestoreAnyChirp       Permission = "chirps:restore:any"
+	HideChirps            Permission = "chirps:hide"
+	SuspendUsers          Permission = "users:suspend"
+	ReadReports           Permission = "reports:read"
+	ResolveReports        Permission = "reports:resolve"
+	ReadModerationActions Permission = "moderation:actions:read"
+	ManageModerationRules Permission = "moderation:rules:manage"
+	ManageRoles           Permission = "roles:manage"
+	ReadMetrics           Permission = "admin:metrics:read"
+	ResetData             Permission = "admin:reset"
+)
+
+const wildcard = "*"
+
+type Set map[Permission]bool
+
+func NewSet(permissions ...string) Set {
+	set := Set{}
+	for _, permission := range permissions {
+		set[Permission(permission)] = true
+	}
+	return set
+}
+
+// Has reports whether the set grants permission, either directly or through
+// a wildcard on one of its prefixes.
+func (s Set) Has(permission Permission) bool {
+	if s[permission] {
+		return true
+	}
+	segments := strings.Split(string(permission), ":")
+	for i := len(segments) - 1; i >= 0; i-- {
+		prefix := strings.Join(append(segments[:i:i], wildcard), ":")
+		if s[Permission(prefix)] {
+			return true
+		}
+	}
+	return false
+}
+
+// List returns the permissions in the set in sorted order.
+func (s Set) List() []Permission {
+	permissions := make([]Permission, 0, len(s))
+	for permission := range s {
+		permissions = append(permissions, permission)
+	}
+	sort.Slice(permissions, func(i, j int) bool {
+		return permissions[i] < permissions[j]
+	})
+	return permissions
+}
+
+// Principal is the authenticated user of a request and what they may do.
+type Principal struct {
+	UserID      uuid.UUID
+	Permissions Set
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(contextKey{}).(Principal)
+	return principal, ok
+}
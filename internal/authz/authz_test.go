@@ -0,0 +1,100 @@
+package authz_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/authz"
+)
+
+func TestSetHas(t *testing.T) {
+	testCases := []struct {
+		name        string
+		granted     []string
+		permission  authz.Permission
+		expectedHas bool
+	}{
+		{
+			name:        "exact permission",
+			granted:     []string{"chirps:delete:any"},
+			permission:  authz.DeleteAnyChirp,
+			expectedHas: true,
+		},
+		{
+			name:        "missing permission",
+			granted:     []string{"chirps:delete:any"},
+			permission:  authz.SuspendUsers,
+			expectedHas: false,
+		},
+		{
+			name:        "wildcard on a prefix",
+			granted:     []string{"chirps:*"},
+			permission:  authz.DeleteAnyChirp,
+			expectedHas: true,
+		},
+		{
+			name:        "wildcard on a deeper prefix",
+			granted:     []string{"chirps:delete:*"},
+			permission:  authz.DeleteAnyChirp,
+			expectedHas: true,
+		},
+		{
+			name:        "wildcard on another prefix",
+			granted:     []string{"users:*"},
+			permission:  authz.DeleteAnyChirp,
+			expectedHas: false,
+		},
+		{
+			name:        "prefix without wildcard",
+			granted:     []string{"chirps"},
+			permission:  authz.DeleteAnyChirp,
+			expectedHas: false,
+		},
+		{
+			name:        "everything",
+			granted:     []string{"*"},
+			permission:  authz.ResetData,
+			expectedHas: true,
+		},
+		{
+			name:        "empty set",
+			granted:     nil,
+			permission:  authz.ReadReports,
+			expectedHas: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			set := authz.NewSet(tc.granted...)
+			if got := set.Has(tc.permission); got != tc.expectedHas {
+				t.Errorf("Has(%q) = %v, want %v", tc.permission, got, tc.expectedHas)
+			}
+		})
+	}
+}
+
+func TestSetList(t *testing.T) {
+	set := authz.NewSet("users:suspend", "chirps:hide", "reports:read")
+	expected := []authz.Permission{authz.HideChirps, authz.ReadReports, authz.SuspendUsers}
+	if got := set.List(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("List() = %v, want %v", got, expected)
+	}
+}
+
+func TestContext(t *testing.T) {
+	if _, ok := authz.FromContext(context.Background()); ok {
+		t.Fatal("FromContext() found a principal in an empty context")
+	}
+
+	principal := authz.Principal{UserID: uuid.New(), Permissions: authz.NewSet("reports:read")}
+	got, ok := authz.FromContext(authz.NewContext(context.Background(), principal))
+	if !ok {
+		t.Fatal("FromContext() did not find the principal")
+	}
+	if got.UserID != principal.UserID || !got.Permissions.Has(authz.ReadReports) {
+		t.Errorf("FromContext() = %v, want %v", got, principal)
+	}
+}
@@ -0,0 +1,133 @@
+package broadcast
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultBufferSize  = 64
+	DefaultHistorySize = 1024
+)
+
+// Event is a single message delivered to subscribers. IDs increase
+// monotonically, also across restarts, so clients can resume with the last
+// ID they have seen. Key is the user an event belongs to, e.g. the author
+// of a chirp or the recipient of a notification, and is what filters
+// usually match on.
+type Event struct {
+	ID   uint64
+	Type string
+	Key  uuid.UUID
+	Data json.RawMessage
+}
+
+// Filter decides whether a subscriber is interested in an event. A nil Filter
+// accepts everything.
+type Filter func(Event) bool
+
+// Subscription receives events on Events until it is closed. Evicted is
+// closed when the subscriber could not keep up and was dropped; it should
+// reconnect and resume from the last event it handled.
+type Subscription struct {
+	Events  <-chan Event
+	Evicted <-chan struct{}
+
+	events  chan Event
+	evicted chan struct{}
+	filter  Filter
+}
+
+// Broadcaster fans published events out to subscribers. Every subscriber has
+// its own buffer, and a subscriber whose buffer is full is evicted instead of
+// blocking the publisher.
+type Broadcaster struct {
+	mu          sync.Mutex
+	nextID      uint64
+	bufferSize  int
+	historySize int
+	history     []Event
+	subscribers map[*Subscription]struct{}
+}
+
+func NewBroadcaster(bufferSize, historySize int) *Broadcaster {
+	return &Broadcaster{
+		nextID:      uint64(time.Now().UnixMicro()),
+		bufferSize:  bufferSize,
+		historySize: historySize,
+		subscribers: map[*Subscription]struct{}{},
+	}
+}
+
+func (b *Broadcaster) Publish(eventType string, key uuid.UUID, data json.RawMessage) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	event := Event{ID: b.nextID, Type: eventType, Key: key, Data: data}
+
+	b.history = append(b.history, event)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for subscription := range b.subscribers {
+		if subscription.filter != nil && !subscription.filter(event) {
+			continue
+		}
+		select {
+		case subscription.events <- event:
+		default:
+			b.evict(subscription)
+		}
+	}
+	return event
+}
+
+// Subscribe registers a new subscriber. When lastEventID is not zero the
+// retained events after it that pass filter are returned for replay; they are
+// not sent on the subscription, so nothing is delivered twice.
+func (b *Broadcaster) Subscribe(lastEventID uint64, filter Filter) (*Subscription, []Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	events := make(chan Event, b.bufferSize)
+	evicted := make(chan struct{})
+	subscription := &Subscription{
+		Events:  events,
+		Evicted: evicted,
+		events:  events,
+		evicted: evicted,
+		filter:  filter,
+	}
+	b.subscribers[subscription] = struct{}{}
+
+	replay := []Event{}
+	if lastEventID != 0 {
+		for _, event := range b.history {
+			if event.ID > lastEventID && (filter == nil || filter(event)) {
+				replay = append(replay, event)
+			}
+		}
+	}
+	return subscription, replay
+}
+
+func (b *Broadcaster) Unsubscribe(subscription *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.subscribers, subscription)
+}
+
+// evict must be called with b.mu held.
+func (b *Broadcaster) evict(subscription *Subscription) {
+	if _, ok := b.subscribers[subscription]; !ok {
+		return
+	}
+	delete(b.subscribers, subscription)
+	close(subscription.evicted)
+}
@@ -0,0 +1,77 @@
+package broadcast_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/broadcast"
+)
+
+func TestPublishSubscribe(t *testing.T) {
+	broadcaster := broadcast.NewBroadcaster(4, 16)
+	author := uuid.New()
+	other := uuid.New()
+
+	all, _ := broadcaster.Subscribe(0, nil)
+	filtered, _ := broadcaster.Subscribe(0, func(event broadcast.Event) bool {
+		return event.Key == author
+	})
+
+	first := broadcaster.Publish("chirp.created", author, json.RawMessage(`{}`))
+	broadcaster.Publish("chirp.created", other, json.RawMessage(`{}`))
+
+	if got := len(all.Events); got != 2 {
+		t.Errorf("expected 2 events for unfiltered subscriber, got %d", got)
+	}
+	if got := len(filtered.Events); got != 1 {
+		t.Fatalf("expected 1 event for filtered subscriber, got %d", got)
+	}
+	if event := <-filtered.Events; event.ID != first.ID {
+		t.Errorf("expected event %d, got %d", first.ID, event.ID)
+	}
+}
+
+func TestSubscribeReplay(t *testing.T) {
+	broadcaster := broadcast.NewBroadcaster(4, 2)
+	author := uuid.New()
+
+	first := broadcaster.Publish("chirp.created", author, nil)
+	second := broadcaster.Publish("chirp.created", author, nil)
+	third := broadcaster.Publish("chirp.deleted", author, nil)
+
+	_, replay := broadcaster.Subscribe(first.ID, nil)
+	if len(replay) != 2 || replay[0].ID != second.ID || replay[1].ID != third.ID {
+		t.Errorf("expected replay of events %d and %d, got %v", second.ID, third.ID, replay)
+	}
+
+	_, replay = broadcaster.Subscribe(0, nil)
+	if len(replay) != 0 {
+		t.Errorf("expected no replay without Last-Event-ID, got %d events", len(replay))
+	}
+}
+
+func TestSlowConsumerEviction(t *testing.T) {
+	broadcaster := broadcast.NewBroadcaster(1, 16)
+	author := uuid.New()
+
+	slow, _ := broadcaster.Subscribe(0, nil)
+	broadcaster.Publish("chirp.created", author, nil)
+
+	select {
+	case <-slow.Evicted:
+		t.Fatal("subscriber evicted before its buffer was full")
+	default:
+	}
+
+	broadcaster.Publish("chirp.created", author, nil)
+
+	select {
+	case <-slow.Evicted:
+	default:
+		t.Fatal("slow subscriber was not evicted")
+	}
+
+	// Publishing after eviction must not block or panic.
+	broadcaster.Publish("chirp.created", author, nil)
+}
@@ -7,23 +7,71 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES ( gen_random_uuid(), NOW(), NOW(), $1, $2)
-RETURNING id, created_at, updated_at, body, user_id
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, parent_id, quoted_chirp_id)
+VALUES ( gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4)
+RETURNING id, created_at, updated_at, body, user_id, search_vector, parent_id, quoted_chirp_id, deleted_at, hidden_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.NullUUID
+	Body          string
+	UserID        uuid.NullUUID
+	ParentID      uuid.NullUUID
+	QuotedChirpID uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.ParentID,
+		arg.QuotedChirpID,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.ParentID,
+		&i.QuotedChirpID,
+		&i.DeletedAt,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const createChirpWithID = `-- name: CreateChirpWithID :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, parent_id, quoted_chirp_id)
+VALUES ( $1, NOW(), NOW(), $2, $3, $4, $5)
+RETURNING id, created_at, updated_at, body, user_id, search_vector, parent_id, quoted_chirp_id, deleted_at, hidden_at
+`
+
+type CreateChirpWithIDParams struct {
+	ID            uuid.UUID
+	Body          string
+	UserID        uuid.NullUUID
+	ParentID      uuid.NullUUID
+	QuotedChirpID uuid.NullUUID
+}
+
+func (q *Queries) CreateChirpWithID(ctx context.Context, arg CreateChirpWithIDParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createChirpWithID,
+		arg.ID,
+		arg.Body,
+		arg.UserID,
+		arg.ParentID,
+		arg.QuotedChirpID,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -31,6 +79,11 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.SearchVector,
+		&i.ParentID,
+		&i.QuotedChirpID,
+		&i.DeletedAt,
+		&i.HiddenAt,
 	)
 	return i, err
 }
@@ -44,24 +97,49 @@ func (q *Queries) DeleteChirp(ctx context.Context) error {
 	return err
 }
 
-const deleteSpecificChirp = `-- name: DeleteSpecificChirp :exec
-DELETE FROM chirps
-WHERE user_id = $1 AND id = $2
+const hideChirp = `-- name: HideChirp :one
+UPDATE chirps
+SET deleted_at = COALESCE(deleted_at, $2), hidden_at = $2
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, search_vector, parent_id, quoted_chirp_id, deleted_at, hidden_at
 `
 
-type DeleteSpecificChirpParams struct {
-	UserID uuid.NullUUID
-	ID     uuid.UUID
+type HideChirpParams struct {
+	ID       uuid.UUID
+	HiddenAt sql.NullTime
 }
 
-func (q *Queries) DeleteSpecificChirp(ctx context.Context, arg DeleteSpecificChirpParams) error {
-	_, err := q.db.ExecContext(ctx, deleteSpecificChirp, arg.UserID, arg.ID)
+func (q *Queries) HideChirp(ctx context.Context, arg HideChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, hideChirp, arg.ID, arg.HiddenAt)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.ParentID,
+		&i.QuotedChirpID,
+		&i.DeletedAt,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const purgeChirps = `-- name: PurgeChirps :exec
+DELETE FROM chirps
+WHERE id = ANY($1::uuid[]) AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) PurgeChirps(ctx context.Context, ids []uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, purgeChirps, pq.Array(ids))
 	return err
 }
 
 const readAllChirps = `-- name: ReadAllChirps :many
-SELECT id, created_at, updated_at, body, user_id
-FROM chirps
+SELECT id, created_at, updated_at, body, user_id, search_vector, parent_id, quoted_chirp_id, deleted_at, hidden_at FROM chirps
+WHERE deleted_at IS NULL
 ORDER BY created_at
 `
 
@@ -80,6 +158,11 @@ func (q *Queries) ReadAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.SearchVector,
+			&i.ParentID,
+			&i.QuotedChirpID,
+			&i.DeletedAt,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -95,9 +178,8 @@ func (q *Queries) ReadAllChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const readChirp = `-- name: ReadChirp :one
-SELECT id, created_at, updated_at, body, user_id
-FROM chirps
-WHERE id = $1
+SELECT id, created_at, updated_at, body, user_id, search_vector, parent_id, quoted_chirp_id, deleted_at, hidden_at FROM chirps
+WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) ReadChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -109,6 +191,558 @@ func (q *Queries) ReadChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.SearchVector,
+		&i.ParentID,
+		&i.QuotedChirpID,
+		&i.DeletedAt,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const readChirpAncestors = `-- name: ReadChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT c.id, c.parent_id, 0 AS depth
+    FROM chirps c
+    WHERE c.id = $1::uuid
+    UNION ALL
+    SELECT c.id, c.parent_id, a.depth + 1
+    FROM chirps c
+    JOIN ancestors a ON c.id = a.parent_id
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.parent_id, chirps.quoted_chirp_id, chirps.deleted_at, chirps.hidden_at FROM chirps
+JOIN ancestors ON chirps.id = ancestors.id
+WHERE ancestors.depth > 0
+AND chirps.deleted_at IS NULL
+ORDER BY ancestors.depth DESC
+`
+
+func (q *Queries) ReadChirpAncestors(ctx context.Context, chirpID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, readChirpAncestors, chirpID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.ParentID,
+			&i.QuotedChirpID,
+			&i.DeletedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const readChirpDescendants = `-- name: ReadChirpDescendants :many
+WITH RECURSIVE descendants AS (
+    SELECT c.id
+    FROM chirps c
+    WHERE c.parent_id = $1::uuid
+    UNION ALL
+    SELECT c.id
+    FROM chirps c
+    JOIN descendants d ON c.parent_id = d.id
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.parent_id, chirps.quoted_chirp_id, chirps.deleted_at, chirps.hidden_at FROM chirps
+JOIN descendants ON chirps.id = descendants.id
+WHERE chirps.deleted_at IS NULL
+AND ($2::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) > ($2::timestamp, $3::uuid))
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT $4
+`
+
+type ReadChirpDescendantsParams struct {
+	RootID          uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageLimit       int32
+}
+
+func (q *Queries) ReadChirpDescendants(ctx context.Context, arg ReadChirpDescendantsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, readChirpDescendants,
+		arg.RootID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.ParentID,
+			&i.QuotedChirpID,
+			&i.DeletedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const readChirpForUpdate = `-- name: ReadChirpForUpdate :one
+SELECT id, created_at, updated_at, body, user_id, search_vector, parent_id, quoted_chirp_id, deleted_at, hidden_at FROM chirps
+WHERE id = $1 AND deleted_at IS NULL
+FOR UPDATE
+`
+
+func (q *Queries) ReadChirpForUpdate(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, readChirpForUpdate, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.ParentID,
+		&i.QuotedChirpID,
+		&i.DeletedAt,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const readChirpIncludingDeleted = `-- name: ReadChirpIncludingDeleted :one
+SELECT id, created_at, updated_at, body, user_id, search_vector, parent_id, quoted_chirp_id, deleted_at, hidden_at FROM chirps
+WHERE id = $1
+`
+
+func (q *Queries) ReadChirpIncludingDeleted(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, readChirpIncludingDeleted, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.ParentID,
+		&i.QuotedChirpID,
+		&i.DeletedAt,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const readChirpReplies = `-- name: ReadChirpReplies :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, parent_id, quoted_chirp_id, deleted_at, hidden_at FROM chirps
+WHERE parent_id = $1::uuid
+AND deleted_at IS NULL
+AND ($2::timestamp IS NULL
+    OR (created_at, id) > ($2::timestamp, $3::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT $4
+`
+
+type ReadChirpRepliesParams struct {
+	ParentID        uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageLimit       int32
+}
+
+func (q *Queries) ReadChirpReplies(ctx context.Context, arg ReadChirpRepliesParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, readChirpReplies,
+		arg.ParentID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.ParentID,
+			&i.QuotedChirpID,
+			&i.DeletedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const readChirpsAsc = `-- name: ReadChirpsAsc :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, parent_id, quoted_chirp_id, deleted_at, hidden_at FROM chirps
+WHERE deleted_at IS NULL
+AND ($1::uuid IS NULL OR user_id = $1::uuid)
+AND ($2::timestamp IS NULL
+    OR (created_at, id) > ($2::timestamp, $3::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT $4
+`
+
+type ReadChirpsAscParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageLimit       int32
+}
+
+func (q *Queries) ReadChirpsAsc(ctx context.Context, arg ReadChirpsAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, readChirpsAsc,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.ParentID,
+			&i.QuotedChirpID,
+			&i.DeletedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const readChirpsByIDs = `-- name: ReadChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, parent_id, quoted_chirp_id, deleted_at, hidden_at FROM chirps
+WHERE id = ANY($1::uuid[])
+AND deleted_at IS NULL
+`
+
+func (q *Queries) ReadChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, readChirpsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.ParentID,
+			&i.QuotedChirpID,
+			&i.DeletedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const readChirpsDesc = `-- name: ReadChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, parent_id, quoted_chirp_id, deleted_at, hidden_at FROM chirps
+WHERE deleted_at IS NULL
+AND ($1::uuid IS NULL OR user_id = $1::uuid)
+AND ($2::timestamp IS NULL
+    OR (created_at, id) < ($2::timestamp, $3::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type ReadChirpsDescParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageLimit       int32
+}
+
+func (q *Queries) ReadChirpsDesc(ctx context.Context, arg ReadChirpsDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, readChirpsDesc,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.ParentID,
+			&i.QuotedChirpID,
+			&i.DeletedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const readPurgeableChirpIDs = `-- name: ReadPurgeableChirpIDs :many
+SELECT id FROM chirps
+WHERE deleted_at < $1::timestamp
+AND NOT EXISTS (
+    SELECT 1 FROM reports
+    WHERE reports.chirp_id = chirps.id AND reports.resolved_at IS NULL
+)
+ORDER BY deleted_at
+LIMIT $2
+`
+
+type ReadPurgeableChirpIDsParams struct {
+	DeletedBefore time.Time
+	PageLimit     int32
+}
+
+func (q *Queries) ReadPurgeableChirpIDs(ctx context.Context, arg ReadPurgeableChirpIDsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, readPurgeableChirpIDs, arg.DeletedBefore, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreChirp = `-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at = NULL, hidden_at = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING id, created_at, updated_at, body, user_id, search_vector, parent_id, quoted_chirp_id, deleted_at, hidden_at
+`
+
+func (q *Queries) RestoreChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, restoreChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.ParentID,
+		&i.QuotedChirpID,
+		&i.DeletedAt,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const searchChirps = `-- name: SearchChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.parent_id, chirps.quoted_chirp_id, chirps.deleted_at, chirps.hidden_at, ts_rank(search_vector, websearch_to_tsquery('english', $1)) AS rank
+FROM chirps
+WHERE search_vector @@ websearch_to_tsquery('english', $1)
+AND deleted_at IS NULL
+AND ($2::uuid IS NULL OR user_id = $2::uuid)
+ORDER BY rank DESC, created_at DESC, id DESC
+LIMIT $3 OFFSET $4
+`
+
+type SearchChirpsParams struct {
+	Query      string
+	AuthorID   uuid.NullUUID
+	PageLimit  int32
+	PageOffset int32
+}
+
+type SearchChirpsRow struct {
+	Chirp Chirp
+	Rank  float32
+}
+
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps,
+		arg.Query,
+		arg.AuthorID,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsRow
+	for rows.Next() {
+		var i SearchChirpsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.SearchVector,
+			&i.Chirp.ParentID,
+			&i.Chirp.QuotedChirpID,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.HiddenAt,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const softDeleteChirp = `-- name: SoftDeleteChirp :execrows
+UPDATE chirps
+SET deleted_at = $3
+WHERE user_id = $1 AND id = $2 AND deleted_at IS NULL
+`
+
+type SoftDeleteChirpParams struct {
+	UserID    uuid.NullUUID
+	ID        uuid.UUID
+	DeletedAt sql.NullTime
+}
+
+func (q *Queries) SoftDeleteChirp(ctx context.Context, arg SoftDeleteChirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, softDeleteChirp, arg.UserID, arg.ID, arg.DeletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps
+SET updated_at = NOW(), body = $2
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, search_vector, parent_id, quoted_chirp_id, deleted_at, hidden_at
+`
+
+type UpdateChirpBodyParams struct {
+	ID   uuid.UUID
+	Body string
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.ID, arg.Body)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.ParentID,
+		&i.QuotedChirpID,
+		&i.DeletedAt,
+		&i.HiddenAt,
 	)
 	return i, err
 }
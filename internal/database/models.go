@@ -11,21 +11,207 @@ import (
 	"github.com/google/uuid"
 )
 
+type Bookmark struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
 type Chirp struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Body          string
+	UserID        uuid.NullUUID
+	SearchVector  interface{}
+	ParentID      uuid.NullUUID
+	QuotedChirpID uuid.NullUUID
+	DeletedAt     sql.NullTime
+	HiddenAt      sql.NullTime
+}
+
+type ChirpHashtag struct {
+	ChirpID   uuid.UUID
+	HashtagID uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpLike struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpMention struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpRevision struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	Body       string
+	CreatedAt  time.Time
+	ReplacedAt time.Time
+}
+
+type Draft struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	UserID        uuid.UUID
+	Body          string
+	ParentID      uuid.NullUUID
+	QuotedChirpID uuid.NullUUID
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type Hashtag struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Body      string
-	UserID    uuid.NullUUID
+	Tag       string
 }
 
-type RefreshToken struct {
+type Medium struct {
+	ID                   uuid.UUID
+	CreatedAt            time.Time
+	UserID               uuid.UUID
+	ChirpID              uuid.NullUUID
+	Position             sql.NullInt32
+	ContentType          string
+	Width                int32
+	Height               int32
+	StorageKey           string
+	ThumbnailKey         string
+	ThumbnailContentType string
+}
+
+type MfaChallenge struct {
 	Token     string
-	CreatedAt time.Time
-	UpdatedAt time.Time
 	UserID    uuid.UUID
+	CreatedAt time.Time
 	ExpiresAt time.Time
-	RevokedAt sql.NullTime
+	Attempts  int32
+}
+
+type ModerationAction struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	ModeratorID uuid.NullUUID
+	Action      string
+	ReportID    uuid.NullUUID
+	ChirpID     uuid.NullUUID
+	UserID      uuid.NullUUID
+	Note        string
+}
+
+type ModerationRule struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Kind      string
+	Action    string
+	Pattern   string
+}
+
+type Notification struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	ActorID   uuid.NullUUID
+	Type      string
+	ChirpID   uuid.NullUUID
+	ReadAt    sql.NullTime
+}
+
+type Passkey struct {
+	ID             uuid.UUID
+	UserID         uuid.UUID
+	CredentialID   []byte
+	Name           string
+	PublicKey      []byte
+	Algorithm      int64
+	SignCount      int64
+	BackupEligible bool
+	BackedUp       bool
+	Transports     []string
+	CreatedAt      time.Time
+	LastUsedAt     sql.NullTime
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CodeHash  string
+	CreatedAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type RefreshToken struct {
+	Token      string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	ExpiresAt  time.Time
+	RevokedAt  sql.NullTime
+	FamilyID   uuid.UUID
+	UserAgent  string
+	Ip         string
+	LastUsedAt time.Time
+}
+
+type Report struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	ReporterID     uuid.NullUUID
+	ChirpID        uuid.NullUUID
+	UserID         uuid.UUID
+	Reason         string
+	Details        string
+	ResolvedAt     sql.NullTime
+	ResolvedBy     uuid.NullUUID
+	Resolution     sql.NullString
+	ResolutionNote sql.NullString
+}
+
+type Role struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Name      string
+}
+
+type RolePermission struct {
+	RoleID     uuid.UUID
+	Permission string
+}
+
+type ScheduledChirp struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	UserID        uuid.UUID
+	Body          string
+	ParentID      uuid.NullUUID
+	QuotedChirpID uuid.NullUUID
+	MediaIds      []uuid.UUID
+	PublishAt     time.Time
+	FailedAt      sql.NullTime
+	Failure       sql.NullString
+}
+
+type TotpCredential struct {
+	UserID         uuid.UUID
+	Secret         string
+	CreatedAt      time.Time
+	ConfirmedAt    sql.NullTime
+	LastUsedStep   int64
+	FailedAttempts int32
+	LockedUntil    sql.NullTime
 }
 
 type User struct {
@@ -35,4 +221,20 @@ type User struct {
 	Email          string
 	HashedPassword string
 	IsChirpyRed    bool
+	Username       sql.NullString
+	SuspendedAt    sql.NullTime
+}
+
+type UserRole struct {
+	UserID    uuid.UUID
+	RoleID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type WebauthnChallenge struct {
+	Challenge string
+	Ceremony  string
+	UserID    uuid.NullUUID
+	CreatedAt time.Time
+	ExpiresAt time.Time
 }
@@ -0,0 +1,42 @@
+package entities
+
+import "unicode/utf8"
+
+// extract scans body for words introduced by marker, such as "#tag" or
+// "@handle". A marker only counts at the start of the body or after a rune
+// for which isPart is false, so "c#sharp" or "mail@example" are skipped.
+// Matches are passed through normalize and de-duplicated in order of first
+// appearance.
+func extract(body string, marker rune, isPart func(rune) bool, normalize func(string) (string, bool)) []string {
+	found := []string{}
+	seen := map[string]bool{}
+
+	previous := ' '
+	for i := 0; i < len(body); {
+		r, size := utf8.DecodeRuneInString(body[i:])
+		if r != marker || isPart(previous) {
+			previous = r
+			i += size
+			continue
+		}
+
+		end := i + size
+		for end < len(body) {
+			next, nextSize := utf8.DecodeRuneInString(body[end:])
+			if !isPart(next) {
+				break
+			}
+			end += nextSize
+		}
+
+		value, ok := normalize(body[i+size : end])
+		if ok && !seen[value] {
+			seen[value] = true
+			found = append(found, value)
+		}
+
+		previous = r
+		i = end
+	}
+	return found
+}
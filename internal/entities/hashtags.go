@@ -0,0 +1,44 @@
+package entities
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const MaxHashtagLength = 64
+
+// Hashtags returns the normalized, de-duplicated hashtags of body in order of
+// first appearance. A hashtag must contain at least one letter.
+func Hashtags(body string) []string {
+	return extract(body, '#', isTagRune, normalizeHashtag)
+}
+
+// NormalizeHashtag turns user input such as "#Go" into the stored form "go".
+func NormalizeHashtag(tag string) (string, bool) {
+	return normalizeHashtag(strings.TrimPrefix(tag, "#"))
+}
+
+func normalizeHashtag(tag string) (string, bool) {
+	if tag == "" || utf8.RuneCountInString(tag) > MaxHashtagLength {
+		return "", false
+	}
+
+	hasLetter := false
+	for _, r := range tag {
+		if !isTagRune(r) {
+			return "", false
+		}
+		if unicode.IsLetter(r) {
+			hasLetter = true
+		}
+	}
+	if !hasLetter {
+		return "", false
+	}
+	return strings.ToLower(tag), true
+}
+
+func isTagRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
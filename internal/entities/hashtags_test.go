@@ -0,0 +1,78 @@
+package entities_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kwekkwekpatu/chirpy/internal/entities"
+)
+
+func TestHashtags(t *testing.T) {
+	testCases := []struct {
+		name     string
+		body     string
+		expected []string
+	}{
+		{
+			name:     "no hashtags",
+			body:     "just a chirp",
+			expected: []string{},
+		},
+		{
+			name:     "single hashtag",
+			body:     "learning #golang today",
+			expected: []string{"golang"},
+		},
+		{
+			name:     "normalized and de-duplicated",
+			body:     "#Go is great, #go is fast, #GO!",
+			expected: []string{"go"},
+		},
+		{
+			name:     "punctuation ends a hashtag",
+			body:     "#chirpy, #boot_dev.",
+			expected: []string{"chirpy", "boot_dev"},
+		},
+		{
+			name:     "numeric tags are ignored",
+			body:     "we are #1 #2024",
+			expected: []string{},
+		},
+		{
+			name:     "hash inside a word is ignored",
+			body:     "issue#12 and c#sharp",
+			expected: []string{},
+		},
+		{
+			name:     "unicode letters",
+			body:     "#Café time",
+			expected: []string{"café"},
+		},
+		{
+			name:     "lone hash",
+			body:     "# nothing",
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := entities.Hashtags(tc.body)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestNormalizeHashtag(t *testing.T) {
+	tag, ok := entities.NormalizeHashtag("#Chirpy")
+	if !ok || tag != "chirpy" {
+		t.Errorf("expected chirpy, got %q (ok=%v)", tag, ok)
+	}
+
+	_, ok = entities.NormalizeHashtag("not a tag")
+	if ok {
+		t.Error("NormalizeHashtag accepted a tag with spaces")
+	}
+}
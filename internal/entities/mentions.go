@@ -0,0 +1,33 @@
+package entities
+
+import "strings"
+
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 30
+)
+
+// Mentions returns the normalized, de-duplicated usernames mentioned in body
+// as "@handle", in order of first appearance.
+func Mentions(body string) []string {
+	return extract(body, '@', isUsernameRune, NormalizeUsername)
+}
+
+// NormalizeUsername validates a username and returns its stored, lowercase
+// form. Usernames are 3 to 30 ASCII letters, digits or underscores.
+func NormalizeUsername(username string) (string, bool) {
+	if len(username) < MinUsernameLength || len(username) > MaxUsernameLength {
+		return "", false
+	}
+
+	for _, r := range username {
+		if !isUsernameRune(r) {
+			return "", false
+		}
+	}
+	return strings.ToLower(username), true
+}
+
+func isUsernameRune(r rune) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
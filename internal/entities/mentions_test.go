@@ -0,0 +1,72 @@
+package entities_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kwekkwekpatu/chirpy/internal/entities"
+)
+
+func TestMentions(t *testing.T) {
+	testCases := []struct {
+		name     string
+		body     string
+		expected []string
+	}{
+		{
+			name:     "no mentions",
+			body:     "hello world",
+			expected: []string{},
+		},
+		{
+			name:     "mentions are normalized and de-duplicated",
+			body:     "@Alice and @bob_99, thanks @alice!",
+			expected: []string{"alice", "bob_99"},
+		},
+		{
+			name:     "email addresses are not mentions",
+			body:     "mail me at alice@example.com",
+			expected: []string{},
+		},
+		{
+			name:     "too short handle",
+			body:     "hi @al",
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := entities.Mentions(tc.body)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestNormalizeUsername(t *testing.T) {
+	testCases := []struct {
+		username string
+		expected string
+		valid    bool
+	}{
+		{username: "Chirper_1", expected: "chirper_1", valid: true},
+		{username: "ab", valid: false},
+		{username: "has space", valid: false},
+		{username: "émile", valid: false},
+		{username: "a_very_long_username_that_is_too_long", valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.username, func(t *testing.T) {
+			got, ok := entities.NormalizeUsername(tc.username)
+			if ok != tc.valid {
+				t.Fatalf("expected valid=%v, got %v", tc.valid, ok)
+			}
+			if got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -1,23 +1,43 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"os"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/kwekkwekpatu/chirpy/internal/auth"
+	"github.com/kwekkwekpatu/chirpy/internal/broadcast"
 	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/moderation"
+	"github.com/kwekkwekpatu/chirpy/internal/notify"
+	"github.com/kwekkwekpatu/chirpy/internal/storage"
 	"github.com/kwekkwekpatu/chirpy/internal/util"
+	"github.com/kwekkwekpatu/chirpy/internal/webauthn"
 	_ "github.com/lib/pq"
 )
 
 type ApiConfig struct {
-	mu             sync.Mutex
-	fileserverHits int
-	db             *database.Queries
-	platform       string
-	jwtSecret      string
-	polkaKey       string
+	mu                 sync.Mutex
+	fileserverHits     int
+	db                 *database.Queries
+	dbConn             *sql.DB
+	keyring            *auth.Keyring
+	webauthn           *webauthn.RelyingParty
+	polkaKey           string
+	notifier           notify.Notifier
+	chirpEvents        *broadcast.Broadcaster
+	notificationEvents *broadcast.Broadcaster
+	timelineFollowees  *followeeRegistry
+	storage            storage.Backend
+	chirpRestoreWindow time.Duration
+	chirpRetention     time.Duration
+
+	moderation          *moderation.Pipeline
+	moderationRulesFile string
 }
 
 var APIConfig *ApiConfig
@@ -29,10 +49,10 @@ func init() {
 		util.ErrorLogger.Println(err)
 		return
 	}
-	platform := os.Getenv("PLATFORM")
 	dbURL := os.Getenv("DB_URL")
-	jwtSecret := os.Getenv("JWT_SECRET")
 	polkaKey := os.Getenv("POLKA_KEY")
+	moderationRulesFile := os.Getenv("MODERATION_RULES_FILE")
+	adminEmail := os.Getenv("ADMIN_EMAIL")
 	util.InfoLogger.Printf("Succesfully loaded environment variables.")
 
 	util.InfoLogger.Printf("Loading Postgres database.")
@@ -43,5 +63,136 @@ func init() {
 	dbQueries := database.New(db)
 	util.InfoLogger.Printf("Succesfully loaded database.")
 
-	APIConfig = &ApiConfig{db: dbQueries, platform: platform, jwtSecret: jwtSecret, polkaKey: polkaKey}
+	chirpEvents := broadcast.NewBroadcaster(broadcast.DefaultBufferSize, broadcast.DefaultHistorySize)
+	notificationEvents := broadcast.NewBroadcaster(broadcast.DefaultBufferSize, broadcast.DefaultHistorySize)
+	notifier := notify.Multi{notify.NewStore(dbQueries), notify.NewPublisher(notificationEvents)}
+
+	util.InfoLogger.Printf("Loading JWT signing keys.")
+	keyring, err := newKeyring()
+	if err != nil {
+		// Without signing keys no request could be authenticated.
+		util.ErrorLogger.Fatalln(err)
+	}
+	util.InfoLogger.Printf("Succesfully loaded JWT signing keys.")
+
+	util.InfoLogger.Printf("Loading media storage.")
+	storageBackend, err := newStorageBackend()
+	if err != nil {
+		util.ErrorLogger.Fatalln(err)
+	}
+	util.InfoLogger.Printf("Succesfully loaded media storage.")
+
+	// The default rules apply until the configured ones have been loaded.
+	defaultFilters, err := moderation.NewFilters(moderation.DefaultRules())
+	if err != nil {
+		util.ErrorLogger.Println(err)
+	}
+
+	chirpRestoreWindow := durationFromEnv("CHIRP_RESTORE_WINDOW", DefaultChirpRestoreWindow)
+	chirpRetention := durationFromEnv("CHIRP_RETENTION", DefaultChirpRetention)
+	if chirpRetention < chirpRestoreWindow {
+		util.ErrorLogger.Printf("CHIRP_RETENTION is shorter than CHIRP_RESTORE_WINDOW, using %s", chirpRestoreWindow)
+		chirpRetention = chirpRestoreWindow
+	}
+
+	APIConfig = &ApiConfig{db: dbQueries, dbConn: db, notifier: notifier, keyring: keyring, webauthn: newRelyingParty(), polkaKey: polkaKey,
+		chirpEvents: chirpEvents, notificationEvents: notificationEvents, timelineFollowees: newFolloweeRegistry(), storage: storageBackend,
+		chirpRestoreWindow: chirpRestoreWindow, chirpRetention: chirpRetention,
+		moderation: moderation.NewPipeline(defaultFilters...), moderationRulesFile: moderationRulesFile}
+
+	if adminEmail != "" {
+		err = APIConfig.bootstrapAdmin(context.Background(), adminEmail)
+		if err != nil {
+			util.ErrorLogger.Println(err)
+		}
+	}
+
+	util.InfoLogger.Printf("Loading moderation rules.")
+	ruleCount, err := APIConfig.reloadModerationRules(context.Background())
+	if err != nil {
+		util.ErrorLogger.Println(err)
+		return
+	}
+	util.InfoLogger.Printf("Succesfully loaded %d moderation rules.", ruleCount)
+}
+
+// durationFromEnv parses a duration such as "24h" from the environment and
+// falls back when the variable is unset or invalid.
+func durationFromEnv(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		util.ErrorLogger.Printf("Invalid %s %q, using %s", name, value, fallback)
+		return fallback
+	}
+	return duration
+}
+
+// newStorageBackend picks the media storage from STORAGE_BACKEND, which is
+// either "local" (the default) or "s3". The constructors return typed
+// pointers, so errors are returned with an untyped nil to keep the
+// interface comparable to nil.
+func newStorageBackend() (storage.Backend, error) {
+	if os.Getenv("STORAGE_BACKEND") == "s3" {
+		s3, err := storage.NewS3(storage.S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			Region:    os.Getenv("S3_REGION"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return s3, nil
+	}
+
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "/app/media"
+	}
+	local, err := storage.NewLocal(mediaDir)
+	if err != nil {
+		return nil, err
+	}
+	return local, nil
+}
+
+// newKeyring loads the access token keys from JWT_KEYS_DIR, signing with the
+// key named by JWT_SIGNING_KEY_ID. Without a key directory it generates a
+// key, which logs everyone out on every restart.
+func newKeyring() (*auth.Keyring, error) {
+	keysDir := os.Getenv("JWT_KEYS_DIR")
+	if keysDir == "" {
+		util.WarnLogger.Printf("JWT_KEYS_DIR is not set, generating a temporary signing key.")
+		return auth.GenerateKeyring()
+	}
+	return auth.LoadKeyring(keysDir, os.Getenv("JWT_SIGNING_KEY_ID"))
+}
+
+// newRelyingParty describes the site passkeys are registered with.
+// WEBAUTHN_RP_ID is its domain and WEBAUTHN_ORIGINS a comma separated list of
+// the origins its pages are served from.
+func newRelyingParty() *webauthn.RelyingParty {
+	rpID := os.Getenv("WEBAUTHN_RP_ID")
+	if rpID == "" {
+		rpID = "localhost"
+	}
+	rpName := os.Getenv("WEBAUTHN_RP_NAME")
+	if rpName == "" {
+		rpName = "Chirpy"
+	}
+	origins := []string{}
+	for _, origin := range strings.Split(os.Getenv("WEBAUTHN_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	if len(origins) == 0 {
+		origins = []string{"http://localhost:8080"}
+	}
+	return webauthn.NewRelyingParty(rpID, rpName, origins)
 }
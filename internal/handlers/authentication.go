@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/auth"
+	"github.com/kwekkwekpatu/chirpy/internal/authz"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+)
+
+// optionalUserID returns the authenticated user for endpoints that are public
+// but personalise their response. A missing Authorization header is not an
+// error, an invalid token is.
+func (cfg *ApiConfig) optionalUserID(request *http.Request) (uuid.NullUUID, error) {
+	if request.Header.Get("Authorization") == "" {
+		return uuid.NullUUID{}, nil
+	}
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+	return uuid.NullUUID{UUID: userID, Valid: true}, nil
+}
+
+// userPermissions returns what the user may do. Anonymous viewers and
+// suspended users may do nothing beyond what every user can.
+func (cfg *ApiConfig) userPermissions(ctx context.Context, userID uuid.NullUUID) (authz.Set, error) {
+	if !userID.Valid {
+		return authz.Set{}, nil
+	}
+	permissions, err := cfg.db.ReadUserPermissions(ctx, userID.UUID)
+	if err != nil {
+		return nil, err
+	}
+	return authz.NewSet(permissions...), nil
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/auth"
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/pagination"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+)
+
+func (cfg *ApiConfig) BookmarkHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling chirp bookmark.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Reading request ChirpID.")
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid chirpID", err)
+		return
+	}
+
+	_, err = cfg.db.ReadChirp(request.Context(), chirpID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusNotFound, "chirp not found", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Attempting to bookmark chirp %s for user %s", chirpID, userID)
+	err = cfg.db.CreateBookmark(request.Context(), database.CreateBookmarkParams{UserID: userID, ChirpID: chirpID})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to bookmark chirp.", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+	util.InfoLogger.Printf("Successfully bookmarked chirp: %s", chirpID)
+}
+
+func (cfg *ApiConfig) UnbookmarkHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling chirp unbookmark.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Reading request ChirpID.")
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid chirpID", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Attempting to remove bookmark of chirp %s for user %s", chirpID, userID)
+	err = cfg.db.DeleteBookmark(request.Context(), database.DeleteBookmarkParams{UserID: userID, ChirpID: chirpID})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to remove bookmark.", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+	util.InfoLogger.Printf("Successfully removed bookmark of chirp: %s", chirpID)
+}
+
+// BookmarksReadHandler lists the bookmarks of the authenticated user. Unlike
+// likes, bookmarks are private, so there is no per-user route.
+func (cfg *ApiConfig) BookmarksReadHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling reading of bookmarks.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	pageParams, err := pagination.ParseParams(request.URL.Query())
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	cursorCreatedAt, cursorID := cursorArgs(pageParams.Cursor)
+
+	util.InfoLogger.Printf("Loading chirps bookmarked by user: %s", userID)
+	bookmarkedChirps, err := cfg.db.ReadBookmarkedChirps(request.Context(), database.ReadBookmarkedChirpsParams{
+		UserID:          userID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		PageLimit:       int32(pageParams.Limit + 1),
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read bookmarks", err)
+		return
+	}
+
+	// Ordered by the time of the bookmark, like the liked chirps page.
+	responseBody := ChirpPage{Chirps: ChirpSlice{}}
+	if len(bookmarkedChirps) > pageParams.Limit {
+		bookmarkedChirps = bookmarkedChirps[:pageParams.Limit]
+		last := bookmarkedChirps[len(bookmarkedChirps)-1]
+		responseBody.NextCursor = pagination.EncodeCursor(pagination.Cursor{CreatedAt: last.BookmarkedAt, ID: last.Chirp.ID})
+	}
+	for _, bookmarkedChirp := range bookmarkedChirps {
+		responseBody.Chirps = append(responseBody.Chirps, chirpFromDatabase(bookmarkedChirp.Chirp))
+	}
+
+	err = cfg.hydrateChirps(request.Context(), responseBody.Chirps, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to load chirp details", err)
+		return
+	}
+
+	respondWithChirpPage(writer, request, responseBody)
+	util.InfoLogger.Printf("Successfully read bookmarks.")
+}
+
+// attachBookmarks sets bookmarked_by_me. Bookmarks are only ever visible to
+// their owner, so anonymous viewers never see them.
+func (cfg *ApiConfig) attachBookmarks(ctx context.Context, chirps ChirpSlice, viewerID uuid.NullUUID) error {
+	if len(chirps) == 0 || !viewerID.Valid {
+		return nil
+	}
+
+	chirpIDs := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+
+	bookmarkedIDs, err := cfg.db.ReadBookmarkedChirpIDs(ctx, database.ReadBookmarkedChirpIDsParams{UserID: viewerID.UUID, ChirpIds: chirpIDs})
+	if err != nil {
+		return err
+	}
+
+	bookmarked := make(map[uuid.UUID]bool, len(bookmarkedIDs))
+	for _, chirpID := range bookmarkedIDs {
+		bookmarked[chirpID] = true
+	}
+	for i := range chirps {
+		chirps[i].BookmarkedByMe = bookmarked[chirps[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/auth"
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+)
+
+type ChirpRevision struct {
+	ID         uuid.UUID `json:"id"`
+	ChirpID    uuid.UUID `json:"chirp_id"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+func (cfg *ApiConfig) ChirpUpdateHandler(writer http.ResponseWriter, request *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	util.InfoLogger.Printf("Handling chirp update.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Reading request ChirpID.")
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid chirpID", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Loading request parameter.")
+	decoder := json.NewDecoder(request.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Error decoding parameters.", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Validating the new body of chirp: %s", chirpID)
+	cleanedBody, err := cfg.prepareChirpBody(params.Body)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(request.Context(), nil)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to update chirp.", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.db.WithTx(tx)
+
+	dbChirp, err := queries.ReadChirpForUpdate(request.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		util.RespondWithError(writer, request, http.StatusNotFound, "chirp not found", err)
+		return
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read chirp.", err)
+		return
+	}
+
+	if dbChirp.UserID.UUID != userID {
+		util.RespondWithError(writer, request, http.StatusForbidden, "Chirp does not belong to user", fmt.Errorf("Chirp does not belong to user."))
+		return
+	}
+
+	if isRechirp(dbChirp) {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Rechirps cannot be edited", fmt.Errorf("Chirp %s is a rechirp", chirpID))
+		return
+	}
+
+	if dbChirp.QuotedChirpID.Valid && cleanedBody == "" {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "A quote-chirp needs a body", fmt.Errorf("Empty body for quote-chirp %s", chirpID))
+		return
+	}
+
+	util.InfoLogger.Printf("Storing previous revision of chirp: %s", chirpID)
+	revisionParams := database.CreateChirpRevisionParams{
+		ChirpID:   dbChirp.ID,
+		Body:      dbChirp.Body,
+		CreatedAt: dbChirp.UpdatedAt,
+	}
+	_, err = queries.CreateChirpRevision(request.Context(), revisionParams)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to store chirp revision.", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Attempting to update chirp: %s", chirpID)
+	updatedChirp, err := queries.UpdateChirpBody(request.Context(), database.UpdateChirpBodyParams{ID: chirpID, Body: cleanedBody})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to update chirp.", err)
+		return
+	}
+
+	err = tagChirp(request.Context(), queries, updatedChirp)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to store hashtags.", err)
+		return
+	}
+
+	mentionedIDs, err := mentionUsers(request.Context(), queries, updatedChirp)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to store mentions.", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to update chirp.", err)
+		return
+	}
+
+	cfg.flagChirp(request.Context(), updatedChirp)
+	cfg.notifyMentions(request.Context(), updatedChirp, mentionedIDs)
+
+	responseBody := ChirpSlice{chirpFromDatabase(updatedChirp)}
+	err = cfg.hydrateChirps(request.Context(), responseBody, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to load chirp details", err)
+		return
+	}
+	util.RespondWithJson(writer, request, http.StatusOK, responseBody[0])
+	util.InfoLogger.Printf("Successfully updated chirp: %s", chirpID)
+}
+
+func (cfg *ApiConfig) ChirpRevisionsReadHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling reading of chirp revisions.")
+
+	util.InfoLogger.Printf("Reading request ChirpID.")
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid chirpID", err)
+		return
+	}
+
+	_, err = cfg.db.ReadChirp(request.Context(), chirpID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusNotFound, "chirp not found", err)
+		return
+	}
+
+	dbRevisions, err := cfg.db.ReadChirpRevisions(request.Context(), chirpID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read chirp revisions.", err)
+		return
+	}
+
+	revisions := []ChirpRevision{}
+	for _, revision := range dbRevisions {
+		revisions = append(revisions, ChirpRevision{ID: revision.ID, ChirpID: revision.ChirpID, Body: revision.Body, CreatedAt: revision.CreatedAt, ReplacedAt: revision.ReplacedAt})
+	}
+
+	util.RespondWithJson(writer, request, http.StatusOK, revisions)
+	util.InfoLogger.Printf("Successfully read chirp revisions.")
+}
@@ -1,33 +1,140 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"sort"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/kwekkwekpatu/chirpy/internal/auth"
+	"github.com/kwekkwekpatu/chirpy/internal/authz"
 	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/notify"
+	"github.com/kwekkwekpatu/chirpy/internal/pagination"
 	"github.com/kwekkwekpatu/chirpy/internal/util"
 	_ "github.com/lib/pq"
 )
 
+const MaxChirpLength = 140
+
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	User_ID   uuid.UUID `json:"user_id"`
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Body      string     `json:"body"`
+	User_ID   uuid.UUID  `json:"user_id"`
+	ParentID  *uuid.UUID `json:"parent_id"`
+	LikeCount int64      `json:"like_count"`
+	LikedByMe bool       `json:"liked_by_me"`
+
+	BookmarkedByMe bool `json:"bookmarked_by_me"`
+
+	QuotedChirpID *uuid.UUID     `json:"quoted_chirp_id"`
+	QuotedChirp   *EmbeddedChirp `json:"quoted_chirp,omitempty"`
+	Mentions      []Mention      `json:"mentions"`
+	Media         []Media        `json:"media"`
+
+	// DeletedAt and HiddenAt are only ever set for moderators, nobody else
+	// can read a deleted chirp.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	HiddenAt  *time.Time `json:"hidden_at,omitempty"`
 }
 
 type ChirpSlice []Chirp
 
+// ChirpPage is one page of a chirp list. Lists respond with the bare array
+// of chirps, as GET /api/chirps always has, and name the next page in the
+// NextCursorHeader and Link headers.
+type ChirpPage struct {
+	Chirps     ChirpSlice
+	NextCursor string
+}
+
+const NextCursorHeader = "X-Next-Cursor"
+
+func chirpFromDatabase(dbChirp database.Chirp) Chirp {
+	chirp := Chirp{ID: dbChirp.ID, CreatedAt: dbChirp.CreatedAt, UpdatedAt: dbChirp.UpdatedAt, Body: dbChirp.Body, User_ID: dbChirp.UserID.UUID, Mentions: []Mention{}, Media: []Media{}}
+	if dbChirp.ParentID.Valid {
+		parentID := dbChirp.ParentID.UUID
+		chirp.ParentID = &parentID
+	}
+	if dbChirp.QuotedChirpID.Valid {
+		quotedChirpID := dbChirp.QuotedChirpID.UUID
+		chirp.QuotedChirpID = &quotedChirpID
+	}
+	if dbChirp.DeletedAt.Valid {
+		deletedAt := dbChirp.DeletedAt.Time
+		chirp.DeletedAt = &deletedAt
+	}
+	if dbChirp.HiddenAt.Valid {
+		hiddenAt := dbChirp.HiddenAt.Time
+		chirp.HiddenAt = &hiddenAt
+	}
+	return chirp
+}
+
+// hydrateChirps loads everything a chirp response embeds that is not stored
+// on the chirp row itself.
+func (cfg *ApiConfig) hydrateChirps(ctx context.Context, chirps ChirpSlice, viewerID uuid.NullUUID) error {
+	err := cfg.attachLikeStats(ctx, chirps, viewerID)
+	if err != nil {
+		return err
+	}
+	err = cfg.attachBookmarks(ctx, chirps, viewerID)
+	if err != nil {
+		return err
+	}
+	err = cfg.attachMentions(ctx, chirps)
+	if err != nil {
+		return err
+	}
+	err = cfg.attachMedia(ctx, chirps)
+	if err != nil {
+		return err
+	}
+	return cfg.attachQuotedChirps(ctx, chirps)
+}
+
+// newChirpPage expects one row more than limit so it can tell whether a
+// further page exists.
+func newChirpPage(dbChirps []database.Chirp, limit int) ChirpPage {
+	page := ChirpPage{Chirps: ChirpSlice{}}
+	if len(dbChirps) > limit {
+		dbChirps = dbChirps[:limit]
+		last := dbChirps[len(dbChirps)-1]
+		page.NextCursor = pagination.EncodeCursor(pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	for _, dbChirp := range dbChirps {
+		page.Chirps = append(page.Chirps, chirpFromDatabase(dbChirp))
+	}
+	return page
+}
+
+func respondWithChirpPage(writer http.ResponseWriter, request *http.Request, page ChirpPage) {
+	if page.NextCursor != "" {
+		next := *request.URL
+		query := next.Query()
+		query.Set("cursor", page.NextCursor)
+		next.RawQuery = query.Encode()
+
+		writer.Header().Set(NextCursorHeader, page.NextCursor)
+		writer.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+	}
+	util.RespondWithJson(writer, request, http.StatusOK, page.Chirps)
+}
+
 func (cfg *ApiConfig) ChirpHandler(writer http.ResponseWriter, request *http.Request) {
 	type parameters struct {
-		Body string `json:"body"`
+		Body          string      `json:"body"`
+		ParentID      *uuid.UUID  `json:"parent_id"`
+		QuotedChirpID *uuid.UUID  `json:"quoted_chirp_id"`
+		MediaIDs      []uuid.UUID `json:"media_ids"`
+		PublishAt     *time.Time  `json:"publish_at"`
 	}
 
 	util.InfoLogger.Printf("Handling chirp creation.")
@@ -49,7 +156,7 @@ func (cfg *ApiConfig) ChirpHandler(writer http.ResponseWriter, request *http.Req
 		return
 	}
 
-	userID, err := auth.ValidateJWT(tokenString, cfg.jwtSecret)
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
 	if err != nil {
 		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
 		return
@@ -57,96 +164,224 @@ func (cfg *ApiConfig) ChirpHandler(writer http.ResponseWriter, request *http.Req
 
 	util.InfoLogger.Printf("Successfully loaded chirp for user_id: %s", userID)
 
-	util.InfoLogger.Printf("Checking if length of chirp is more than 140 characters")
-	if len(params.Body) > 140 {
-		util.RespondWithError(writer, request, http.StatusBadRequest, "Chirp is too long", fmt.Errorf("Chirp is too long"))
+	util.InfoLogger.Printf("Validating the chirp from: %s", userID)
+	chirpParams, fieldErrors := cfg.validateChirp(request.Context(), userID, chirpInput{
+		Body:          params.Body,
+		ParentID:      params.ParentID,
+		QuotedChirpID: params.QuotedChirpID,
+		MediaIDs:      params.MediaIDs,
+	})
+	if len(fieldErrors) > 0 {
+		util.RespondWithError(writer, request, http.StatusBadRequest, fieldErrors[0].Message, fieldErrors[0])
 		return
 	}
 
-	util.InfoLogger.Printf("Cleaning the body of the chirp from: %s", userID)
-	cleaned_body, err := cleanBody(params.Body)
-	if err != nil {
-		util.RespondWithError(writer, request, http.StatusBadRequest, "Failed to clean the body!", err)
+	if params.PublishAt != nil {
+		cfg.scheduleChirp(writer, request, chirpParams, params.MediaIDs, *params.PublishAt)
 		return
 	}
 
-	chirpParams := database.CreateChirpParams{
-		Body: cleaned_body,
-		UserID: uuid.NullUUID{
-			UUID:  userID,
-			Valid: true,
-		},
+	tx, err := cfg.dbConn.BeginTx(request.Context(), nil)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to create chirp.", err)
+		return
 	}
+	defer tx.Rollback()
+	queries := cfg.db.WithTx(tx)
 
 	util.InfoLogger.Printf("Attempting to create chirp with user_id: %s", userID)
-	chirp, err := cfg.db.CreateChirp(request.Context(), chirpParams)
+	chirp, mentionedIDs, err := createChirp(request.Context(), queries, chirpParams, params.MediaIDs)
+	if err != nil {
+		respondWithCreateChirpError(writer, request, err)
+		return
+	}
+
+	err = tx.Commit()
 	if err != nil {
 		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to create chirp.", err)
 		return
 	}
 
 	util.InfoLogger.Printf("Generating response body from chirp.")
-	responseBody := Chirp{ID: chirp.ID, CreatedAt: chirp.CreatedAt, UpdatedAt: chirp.UpdatedAt, Body: chirp.Body, User_ID: chirp.UserID.UUID}
+	responseBody, err := cfg.announceChirp(request.Context(), chirp, mentionedIDs)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to load chirp details", err)
+		return
+	}
 
 	util.RespondWithJson(writer, request, http.StatusCreated, responseBody)
 	util.InfoLogger.Printf("Successfully created a chirp for user: %s", userID)
 	return
 }
 
-func (cfg *ApiConfig) ChirpReadHandler(writer http.ResponseWriter, request *http.Request) {
-	util.InfoLogger.Printf("Handling reading of all chirps.")
+// createChirp writes a new chirp and everything derived from it. It must run
+// inside the caller's transaction.
+func createChirp(ctx context.Context, queries *database.Queries, chirpParams database.CreateChirpParams, mediaIDs []uuid.UUID) (database.Chirp, []uuid.UUID, error) {
+	chirp, err := queries.CreateChirp(ctx, chirpParams)
+	if err != nil {
+		return database.Chirp{}, nil, err
+	}
 
-	util.InfoLogger.Printf("Checking for query parameters.")
-	authorID := request.URL.Query().Get("author_id")
-	authorUUID := uuid.Nil
-	if authorID != "" {
-		util.InfoLogger.Printf("Parsing authorID")
-		var err error
-		authorUUID, err = uuid.Parse(authorID)
+	err = attachChirpMedia(ctx, queries, chirp, mediaIDs)
+	if err != nil {
+		return database.Chirp{}, nil, err
+	}
+
+	mentionedIDs, err := storeChirpEntities(ctx, queries, chirp)
+	if err != nil {
+		return database.Chirp{}, nil, err
+	}
+	return chirp, mentionedIDs, nil
+}
+
+func respondWithCreateChirpError(writer http.ResponseWriter, request *http.Request, err error) {
+	switch {
+	case isUniqueViolation(err):
+		util.RespondWithError(writer, request, http.StatusConflict, "Chirp has already been rechirped", err)
+	case errors.Is(err, errMediaNotFound):
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+	default:
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to create chirp.", err)
+	}
+}
+
+// attachChirpMedia attaches uploaded media to a new chirp. Only unattached
+// media uploaded by the author can be attached.
+func attachChirpMedia(ctx context.Context, queries *database.Queries, chirp database.Chirp, mediaIDs []uuid.UUID) error {
+	if len(mediaIDs) == 0 {
+		return nil
+	}
+
+	util.InfoLogger.Printf("Attaching %d media to chirp: %s", len(mediaIDs), chirp.ID)
+	attached, err := queries.AttachMedia(ctx, database.AttachMediaParams{
+		ChirpID: chirp.ID,
+		Ids:     mediaIDs,
+		UserID:  chirp.UserID.UUID,
+	})
+	if err != nil {
+		return err
+	}
+	if attached != int64(len(mediaIDs)) {
+		util.ErrorLogger.Printf("Attached %d of %d media to chirp: %s", attached, len(mediaIDs), chirp.ID)
+		return errMediaNotFound
+	}
+	return nil
+}
+
+// storeChirpEntities stores the hashtags and mentions of a new chirp and
+// returns the ids of the mentioned users.
+func storeChirpEntities(ctx context.Context, queries *database.Queries, chirp database.Chirp) ([]uuid.UUID, error) {
+	err := tagChirp(ctx, queries, chirp)
+	if err != nil {
+		return nil, err
+	}
+	return mentionUsers(ctx, queries, chirp)
+}
+
+// announceChirp runs the side effects of a committed new chirp: mention and
+// reply notifications and the chirp stream. It returns the hydrated chirp as
+// its author sees it.
+func (cfg *ApiConfig) announceChirp(ctx context.Context, dbChirp database.Chirp, mentionedIDs []uuid.UUID) (Chirp, error) {
+	cfg.flagChirp(ctx, dbChirp)
+	cfg.notifyMentions(ctx, dbChirp, mentionedIDs)
+	if dbChirp.ParentID.Valid {
+		parentChirp, err := cfg.db.ReadChirp(ctx, dbChirp.ParentID.UUID)
 		if err != nil {
-			util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid author_id", err)
-			return
+			util.ErrorLogger.Printf("Failed to read parent chirp %s: %s", dbChirp.ParentID.UUID, err)
+		} else if parentChirp.UserID.Valid {
+			cfg.notify(ctx, notify.Event{
+				Type:      notify.TypeReply,
+				Recipient: parentChirp.UserID.UUID,
+				Actor:     dbChirp.UserID,
+				ChirpID:   uuid.NullUUID{UUID: dbChirp.ID, Valid: true},
+			})
 		}
 	}
 
+	chirps := ChirpSlice{chirpFromDatabase(dbChirp)}
+	err := cfg.hydrateChirps(ctx, chirps, dbChirp.UserID)
+	if err != nil {
+		return Chirp{}, err
+	}
+
+	cfg.publishChirp(ctx, ChirpCreatedEvent, dbChirp)
+	return chirps[0], nil
+}
+
+func (cfg *ApiConfig) ChirpReadHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling reading of chirps.")
+
+	viewerID, err := cfg.optionalUserID(request)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Checking for query parameters.")
+	authorUUID, err := parseAuthorID(request)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid author_id", err)
+		return
+	}
+
 	sortValue := request.URL.Query().Get("sort")
 	sortValue = strings.ToLower(sortValue)
 	if sortValue != "desc" {
 		sortValue = "asc"
 	}
 
+	pageParams, err := pagination.ParseParams(request.URL.Query())
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	cursorCreatedAt, cursorID := cursorArgs(pageParams.Cursor)
+
 	util.InfoLogger.Printf("Loading chirps from database.")
-	chirpArray, err := cfg.db.ReadAllChirps(request.Context())
+	var chirpArray []database.Chirp
+	if sortValue == "desc" {
+		chirpArray, err = cfg.db.ReadChirpsDesc(request.Context(), database.ReadChirpsDescParams{
+			AuthorID:        authorUUID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageLimit:       int32(pageParams.Limit + 1),
+		})
+	} else {
+		chirpArray, err = cfg.db.ReadChirpsAsc(request.Context(), database.ReadChirpsAscParams{
+			AuthorID:        authorUUID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageLimit:       int32(pageParams.Limit + 1),
+		})
+	}
 	if err != nil {
 		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read chirps", err)
 		return
 	}
 	util.InfoLogger.Printf("Succesfully loaded chirps.")
-	var chirpSlice ChirpSlice
 
 	util.InfoLogger.Printf("Generating response body from chirps.")
-	for _, chirp := range chirpArray {
-		if authorUUID != uuid.Nil && chirp.UserID.UUID != authorUUID {
-			continue
-		}
-		chirpSlice = append(chirpSlice, Chirp{ID: chirp.ID, CreatedAt: chirp.CreatedAt, UpdatedAt: chirp.UpdatedAt, Body: chirp.Body, User_ID: chirp.UserID.UUID})
+	responseBody := newChirpPage(chirpArray, pageParams.Limit)
+	err = cfg.hydrateChirps(request.Context(), responseBody.Chirps, viewerID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to load chirp details", err)
+		return
 	}
 
-	sort.Slice(chirpSlice, func(i, j int) bool {
-		if sortValue == "desc" {
-			return chirpSlice[i].CreatedAt.After(chirpSlice[j].CreatedAt)
-		}
-		return chirpSlice[i].CreatedAt.Before(chirpSlice[j].CreatedAt)
-	})
-
 	util.InfoLogger.Printf("Attempting to Marshal response.")
-	util.RespondWithJson(writer, request, http.StatusOK, chirpSlice)
+	respondWithChirpPage(writer, request, responseBody)
 	return
 }
 
 func (cfg *ApiConfig) ChirpSpecificReadHandler(writer http.ResponseWriter, request *http.Request) {
 	util.InfoLogger.Printf("Handling reading of chirp.")
 
+	viewerID, err := cfg.optionalUserID(request)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
 	util.InfoLogger.Printf("Reading request ChirpID.")
 	chirpIDString := request.PathValue("chirpID")
 	chirpID, err := uuid.Parse(chirpIDString)
@@ -155,13 +390,29 @@ func (cfg *ApiConfig) ChirpSpecificReadHandler(writer http.ResponseWriter, reque
 		return
 	}
 
-	dbChirp, err := cfg.db.ReadChirp(request.Context(), chirpID)
+	permissions, err := cfg.userPermissions(request.Context(), viewerID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read permissions", err)
+		return
+	}
+
+	readChirp := cfg.db.ReadChirp
+	if permissions.Has(authz.ReadDeletedChirps) {
+		util.InfoLogger.Printf("Viewer may read deleted chirps.")
+		readChirp = cfg.db.ReadChirpIncludingDeleted
+	}
+	dbChirp, err := readChirp(request.Context(), chirpID)
 	if err != nil {
 		util.RespondWithError(writer, request, http.StatusNotFound, "chirp not found", err)
 		return
 	}
-	responseBody := Chirp{ID: dbChirp.ID, CreatedAt: dbChirp.CreatedAt, UpdatedAt: dbChirp.UpdatedAt, Body: dbChirp.Body, User_ID: dbChirp.UserID.UUID}
-	util.RespondWithJson(writer, request, http.StatusOK, responseBody)
+	responseBody := ChirpSlice{chirpFromDatabase(dbChirp)}
+	err = cfg.hydrateChirps(request.Context(), responseBody, viewerID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to load chirp details", err)
+		return
+	}
+	util.RespondWithJson(writer, request, http.StatusOK, responseBody[0])
 
 	util.InfoLogger.Printf("Successfully read chirp.")
 	return
@@ -177,7 +428,7 @@ func (cfg *ApiConfig) ChirpDeleteSpecificHandler(writer http.ResponseWriter, req
 		return
 	}
 
-	userID, err := auth.ValidateJWT(tokenString, cfg.jwtSecret)
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
 	if err != nil {
 		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
 		return
@@ -200,49 +451,82 @@ func (cfg *ApiConfig) ChirpDeleteSpecificHandler(writer http.ResponseWriter, req
 	}
 
 	if dbChirp.UserID.UUID != userID {
-		util.RespondWithError(writer, request, http.StatusForbidden, "Chirp does not belong to user", fmt.Errorf("Chirp does not belong to user."))
+		permissions, err := cfg.userPermissions(request.Context(), uuid.NullUUID{UUID: userID, Valid: true})
+		if err != nil {
+			util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read permissions", err)
+			return
+		}
+		if !permissions.Has(authz.DeleteAnyChirp) {
+			util.RespondWithError(writer, request, http.StatusForbidden, "Chirp does not belong to user", fmt.Errorf("Chirp does not belong to user."))
+			return
+		}
+
+		util.InfoLogger.Printf("User %s deletes chirp %s as a moderator", userID, chirpID)
+		err = cfg.hideChirp(request.Context(), userID, dbChirp)
+		if err != nil {
+			util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to delete chirp.", err)
+			return
+		}
+
+		writer.WriteHeader(http.StatusNoContent)
+		cfg.publishChirpEvent(ChirpDeletedEvent, dbChirp.UserID.UUID, map[string]uuid.UUID{"id": chirpID})
+		util.InfoLogger.Printf("Successfully deleted chirp: %s", chirpID)
 		return
 	}
 
-	chirpParams := database.DeleteSpecificChirpParams{
-		ID:     chirpID,
-		UserID: dbChirp.UserID,
+	// The restore window and the purge compare deleted_at against Go's
+	// clock, so it is written from Go as well.
+	chirpParams := database.SoftDeleteChirpParams{
+		ID:        chirpID,
+		UserID:    dbChirp.UserID,
+		DeletedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
 	}
 
+	// The row and its media stay until the purge job removes them, so the
+	// chirp can still be restored.
 	util.InfoLogger.Printf("Attempting to delete chirp")
-	err = cfg.db.DeleteSpecificChirp(request.Context(), chirpParams)
+	deleted, err := cfg.db.SoftDeleteChirp(request.Context(), chirpParams)
 	if err != nil {
 		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to delete chirp.", err)
 		return
 	}
+	if deleted == 0 {
+		util.RespondWithError(writer, request, http.StatusNotFound, "chirp not found", fmt.Errorf("Chirp %s was already deleted", chirpID))
+		return
+	}
 
 	writer.WriteHeader(http.StatusNoContent)
+	cfg.publishChirpEvent(ChirpDeletedEvent, userID, map[string]uuid.UUID{"id": chirpID})
 	util.InfoLogger.Printf("Successfully deleted chirp: %s", chirpID)
 	return
 }
 
-func cleanBody(body string) (string, error) {
-	if body == "" {
-		return body, nil
+func (cfg *ApiConfig) prepareChirpBody(body string) (string, error) {
+	util.InfoLogger.Printf("Checking if length of chirp is more than %d characters", MaxChirpLength)
+	if len(body) > MaxChirpLength {
+		return "", fmt.Errorf("Chirp is too long")
 	}
 
-	unsafeWords := map[string]bool{
-		"kerfuffle": true,
-		"sharbert":  true,
-		"fornax":    true,
+	result := cfg.moderation.Moderate(body)
+	if result.Rejected() {
+		for _, match := range result.Matches {
+			util.InfoLogger.Printf("Chirp matched %s rule %q: %s", match.Filter, match.Rule, match.Action)
+		}
+		return "", fmt.Errorf("Chirp contains content that is not allowed")
 	}
+	return result.Body, nil
+}
 
-	splitBody := strings.Split(body, " ")
-	if splitBody == nil {
-		return "", fmt.Errorf("Cannot split body")
+func parseAuthorID(request *http.Request) (uuid.NullUUID, error) {
+	authorID := request.URL.Query().Get("author_id")
+	if authorID == "" {
+		return uuid.NullUUID{}, nil
 	}
 
-	for i, word := range splitBody {
-		if unsafeWords[strings.ToLower(word)] {
-			splitBody[i] = "****"
-		}
+	util.InfoLogger.Printf("Parsing authorID")
+	parsedID, err := uuid.Parse(authorID)
+	if err != nil {
+		return uuid.NullUUID{}, err
 	}
-
-	cleanedBody := strings.Join(splitBody, " ")
-	return cleanedBody, nil
+	return uuid.NullUUID{UUID: parsedID, Valid: true}, nil
 }
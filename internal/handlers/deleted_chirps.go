@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/auth"
+	"github.com/kwekkwekpatu/chirpy/internal/authz"
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+)
+
+// Deleted chirps are only hidden at first. Their owner can restore them
+// within the restore window, unless a moderator hid them, and moderators can
+// restore them until the purge job removes them for good once the retention
+// has passed. Both can be changed through
+// CHIRP_RESTORE_WINDOW and CHIRP_RETENTION.
+const (
+	DefaultChirpRestoreWindow = 24 * time.Hour
+	DefaultChirpRetention     = 30 * 24 * time.Hour
+	ChirpPurgeInterval        = time.Hour
+	ChirpPurgeBatchSize       = 100
+)
+
+func (cfg *ApiConfig) ChirpRestoreHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling chirp restore.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Reading request ChirpID.")
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid chirpID", err)
+		return
+	}
+
+	permissions, err := cfg.userPermissions(request.Context(), uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read permissions", err)
+		return
+	}
+	moderator := permissions.Has(authz.RestoreAnyChirp)
+
+	dbChirp, err := cfg.db.ReadChirpIncludingDeleted(request.Context(), chirpID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusNotFound, "chirp not found", err)
+		return
+	}
+	owner := dbChirp.UserID.Valid && dbChirp.UserID.UUID == userID
+	if !owner && !moderator {
+		util.RespondWithError(writer, request, http.StatusNotFound, "chirp not found", fmt.Errorf("Chirp does not belong to user."))
+		return
+	}
+	if !dbChirp.DeletedAt.Valid {
+		util.RespondWithError(writer, request, http.StatusConflict, "Chirp is not deleted", fmt.Errorf("Chirp %s is not deleted", chirpID))
+		return
+	}
+	if !moderator && dbChirp.HiddenAt.Valid {
+		util.RespondWithError(writer, request, http.StatusForbidden, "Chirp was hidden by a moderator", fmt.Errorf("Chirp %s was hidden at %s", chirpID, dbChirp.HiddenAt.Time))
+		return
+	}
+	if !moderator && time.Since(dbChirp.DeletedAt.Time) > cfg.chirpRestoreWindow {
+		util.RespondWithError(writer, request, http.StatusForbidden, "Restore window has passed", fmt.Errorf("Chirp %s was deleted at %s", chirpID, dbChirp.DeletedAt.Time))
+		return
+	}
+
+	hidden := dbChirp.HiddenAt.Valid
+	tx, err := cfg.dbConn.BeginTx(request.Context(), nil)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to restore chirp.", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.db.WithTx(tx)
+
+	util.InfoLogger.Printf("Attempting to restore chirp: %s", chirpID)
+	dbChirp, err = queries.RestoreChirp(request.Context(), chirpID)
+	if isUniqueViolation(err) {
+		util.RespondWithError(writer, request, http.StatusConflict, "Chirp was already rechirped again", err)
+		return
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to restore chirp.", err)
+		return
+	}
+
+	// Owners restoring their own chirps is not moderation, anything else is.
+	if !owner || hidden {
+		err = queries.CreateModerationAction(request.Context(), database.CreateModerationActionParams{
+			ModeratorID: uuid.NullUUID{UUID: userID, Valid: true},
+			Action:      ModerationActionRestore,
+			ChirpID:     uuid.NullUUID{UUID: chirpID, Valid: true},
+			UserID:      dbChirp.UserID,
+		})
+		if err != nil {
+			util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to record moderation action.", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to restore chirp.", err)
+		return
+	}
+
+	responseBody := ChirpSlice{chirpFromDatabase(dbChirp)}
+	err = cfg.hydrateChirps(request.Context(), responseBody, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to load chirp details", err)
+		return
+	}
+
+	util.RespondWithJson(writer, request, http.StatusOK, responseBody[0])
+	cfg.publishChirp(request.Context(), ChirpRestoredEvent, dbChirp)
+	util.InfoLogger.Printf("Successfully restored chirp: %s", chirpID)
+}
+
+// hideChirp deletes a chirp on behalf of a moderator. Unlike a deletion by
+// its owner the owner cannot undo it, and it goes into the audit trail.
+func (cfg *ApiConfig) hideChirp(ctx context.Context, moderatorID uuid.UUID, dbChirp database.Chirp) error {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	queries := cfg.db.WithTx(tx)
+
+	_, err = queries.HideChirp(ctx, database.HideChirpParams{
+		ID:       dbChirp.ID,
+		HiddenAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = queries.CreateModerationAction(ctx, database.CreateModerationActionParams{
+		ModeratorID: uuid.NullUUID{UUID: moderatorID, Valid: true},
+		Action:      ModerationActionHideChirp,
+		ChirpID:     uuid.NullUUID{UUID: dbChirp.ID, Valid: true},
+		UserID:      dbChirp.UserID,
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RunDeletedChirpPurger removes deleted chirps past their retention, and
+// uploads that were never attached, until ctx is done. Chirps with open
+// reports are kept until a moderator has resolved them; the reports
+// themselves outlive the chirp. Replicas may run it concurrently, purging a
+// row twice is a no-op.
+func (cfg *ApiConfig) RunDeletedChirpPurger(ctx context.Context) {
+	util.InfoLogger.Printf("Starting deleted chirp purger.")
+	ticker := time.NewTicker(ChirpPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			purged, err := cfg.purgeDeletedChirps(ctx)
+			if err != nil {
+				util.ErrorLogger.Printf("Failed to purge deleted chirps: %s", err)
+				break
+			}
+			if purged > 0 {
+				util.InfoLogger.Printf("Purged %d deleted chirps.", purged)
+			}
+			if purged < ChirpPurgeBatchSize {
+				break
+			}
+		}
+		for {
+			purged, err := cfg.purgeStaleMedia(ctx)
+			if err != nil {
+				util.ErrorLogger.Printf("Failed to purge stale media: %s", err)
+				break
+			}
+			if purged > 0 {
+				util.InfoLogger.Printf("Purged %d stale media.", purged)
+			}
+			if purged < ChirpPurgeBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			util.InfoLogger.Printf("Stopping deleted chirp purger.")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeDeletedChirps removes one batch of chirps and afterwards their
+// stored media files, which the database cascade cannot reach.
+func (cfg *ApiConfig) purgeDeletedChirps(ctx context.Context) (int, error) {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	queries := cfg.db.WithTx(tx)
+
+	chirpIDs, err := queries.ReadPurgeableChirpIDs(ctx, database.ReadPurgeableChirpIDsParams{
+		DeletedBefore: time.Now().UTC().Add(-cfg.chirpRetention),
+		PageLimit:     ChirpPurgeBatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(chirpIDs) == 0 {
+		return 0, nil
+	}
+
+	chirpMedia, err := queries.ReadChirpMedia(ctx, chirpIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	err = queries.PurgeChirps(ctx, chirpIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, medium := range chirpMedia {
+		cfg.deleteStoredObjects(ctx, medium.StorageKey, medium.ThumbnailKey)
+	}
+	return len(chirpIDs), nil
+}
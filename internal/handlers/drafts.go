@@ -0,0 +1,382 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/auth"
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/pagination"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+)
+
+// MaxDraftLength only guards against abuse. Drafts may exceed MaxChirpLength;
+// the chirp limits are enforced when a draft is published.
+const MaxDraftLength = 10000
+
+type Draft struct {
+	ID            uuid.UUID  `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Body          string     `json:"body"`
+	User_ID       uuid.UUID  `json:"user_id"`
+	ParentID      *uuid.UUID `json:"parent_id"`
+	QuotedChirpID *uuid.UUID `json:"quoted_chirp_id"`
+}
+
+type DraftPage struct {
+	Drafts     []Draft `json:"drafts"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+type draftParameters struct {
+	Body          string     `json:"body"`
+	ParentID      *uuid.UUID `json:"parent_id"`
+	QuotedChirpID *uuid.UUID `json:"quoted_chirp_id"`
+}
+
+func draftFromDatabase(dbDraft database.Draft) Draft {
+	return Draft{
+		ID:            dbDraft.ID,
+		CreatedAt:     dbDraft.CreatedAt,
+		UpdatedAt:     dbDraft.UpdatedAt,
+		Body:          dbDraft.Body,
+		User_ID:       dbDraft.UserID,
+		ParentID:      uuidPointer(dbDraft.ParentID),
+		QuotedChirpID: uuidPointer(dbDraft.QuotedChirpID),
+	}
+}
+
+func uuidPointer(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
+
+func nullUUID(id *uuid.UUID) uuid.NullUUID {
+	if id == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: *id, Valid: true}
+}
+
+func decodeDraftParameters(request *http.Request) (draftParameters, error) {
+	util.InfoLogger.Printf("Loading request parameter.")
+	decoder := json.NewDecoder(request.Body)
+	params := draftParameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		return draftParameters{}, fmt.Errorf("Error decoding parameters.")
+	}
+	if len(params.Body) > MaxDraftLength {
+		return draftParameters{}, fmt.Errorf("Draft is too long")
+	}
+	return params, nil
+}
+
+func (cfg *ApiConfig) DraftHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling draft creation.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	params, err := decodeDraftParameters(request)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	util.InfoLogger.Printf("Attempting to create draft for user: %s", userID)
+	dbDraft, err := cfg.db.CreateDraft(request.Context(), database.CreateDraftParams{
+		UserID:        userID,
+		Body:          params.Body,
+		ParentID:      nullUUID(params.ParentID),
+		QuotedChirpID: nullUUID(params.QuotedChirpID),
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to create draft.", err)
+		return
+	}
+
+	util.RespondWithJson(writer, request, http.StatusCreated, draftFromDatabase(dbDraft))
+	util.InfoLogger.Printf("Successfully created draft: %s", dbDraft.ID)
+}
+
+func (cfg *ApiConfig) DraftsReadHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling reading of drafts.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	pageParams, err := pagination.ParseParams(request.URL.Query())
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	// Drafts are listed most recently edited first, so the cursor holds
+	// updated_at.
+	cursorUpdatedAt, cursorID := cursorArgs(pageParams.Cursor)
+
+	util.InfoLogger.Printf("Loading drafts of user: %s", userID)
+	dbDrafts, err := cfg.db.ReadDrafts(request.Context(), database.ReadDraftsParams{
+		UserID:          userID,
+		CursorUpdatedAt: cursorUpdatedAt,
+		CursorID:        cursorID,
+		PageLimit:       int32(pageParams.Limit + 1),
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read drafts", err)
+		return
+	}
+
+	responseBody := DraftPage{Drafts: []Draft{}}
+	if len(dbDrafts) > pageParams.Limit {
+		dbDrafts = dbDrafts[:pageParams.Limit]
+		last := dbDrafts[len(dbDrafts)-1]
+		responseBody.NextCursor = pagination.EncodeCursor(pagination.Cursor{CreatedAt: last.UpdatedAt, ID: last.ID})
+	}
+	for _, dbDraft := range dbDrafts {
+		responseBody.Drafts = append(responseBody.Drafts, draftFromDatabase(dbDraft))
+	}
+
+	util.RespondWithJson(writer, request, http.StatusOK, responseBody)
+	util.InfoLogger.Printf("Successfully read drafts.")
+}
+
+// Drafts of other users are reported as not found so their ids do not leak.
+func (cfg *ApiConfig) DraftSpecificReadHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling reading of draft.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Reading request DraftID.")
+	draftID, err := uuid.Parse(request.PathValue("draftID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid draftID", err)
+		return
+	}
+
+	dbDraft, err := cfg.db.ReadDraft(request.Context(), database.ReadDraftParams{ID: draftID, UserID: userID})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusNotFound, "Draft not found", err)
+		return
+	}
+
+	util.RespondWithJson(writer, request, http.StatusOK, draftFromDatabase(dbDraft))
+	util.InfoLogger.Printf("Successfully read draft: %s", draftID)
+}
+
+func (cfg *ApiConfig) DraftUpdateHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling draft update.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Reading request DraftID.")
+	draftID, err := uuid.Parse(request.PathValue("draftID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid draftID", err)
+		return
+	}
+
+	params, err := decodeDraftParameters(request)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	util.InfoLogger.Printf("Attempting to update draft: %s", draftID)
+	dbDraft, err := cfg.db.UpdateDraft(request.Context(), database.UpdateDraftParams{
+		ID:            draftID,
+		UserID:        userID,
+		Body:          params.Body,
+		ParentID:      nullUUID(params.ParentID),
+		QuotedChirpID: nullUUID(params.QuotedChirpID),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		util.RespondWithError(writer, request, http.StatusNotFound, "Draft not found", err)
+		return
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to update draft.", err)
+		return
+	}
+
+	util.RespondWithJson(writer, request, http.StatusOK, draftFromDatabase(dbDraft))
+	util.InfoLogger.Printf("Successfully updated draft: %s", draftID)
+}
+
+func (cfg *ApiConfig) DraftDeleteHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling draft deletion.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Reading request DraftID.")
+	draftID, err := uuid.Parse(request.PathValue("draftID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid draftID", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Attempting to delete draft: %s", draftID)
+	deleted, err := cfg.db.DeleteDraft(request.Context(), database.DeleteDraftParams{ID: draftID, UserID: userID})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to delete draft.", err)
+		return
+	}
+	if deleted == 0 {
+		util.RespondWithError(writer, request, http.StatusNotFound, "Draft not found", fmt.Errorf("No draft %s for user %s", draftID, userID))
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+	util.InfoLogger.Printf("Successfully deleted draft: %s", draftID)
+}
+
+// DraftPublishHandler turns a draft into a chirp. It runs the same checks as
+// ChirpHandler but reports every problem with its field, so clients can
+// point at what to fix. The draft is removed once the chirp exists.
+func (cfg *ApiConfig) DraftPublishHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling draft publishing.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Reading request DraftID.")
+	draftID, err := uuid.Parse(request.PathValue("draftID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid draftID", err)
+		return
+	}
+
+	dbDraft, err := cfg.db.ReadDraft(request.Context(), database.ReadDraftParams{ID: draftID, UserID: userID})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusNotFound, "Draft not found", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Validating draft: %s", draftID)
+	chirpParams, fieldErrors := cfg.validateChirp(request.Context(), userID, chirpInput{
+		Body:          dbDraft.Body,
+		ParentID:      uuidPointer(dbDraft.ParentID),
+		QuotedChirpID: uuidPointer(dbDraft.QuotedChirpID),
+	})
+	if len(fieldErrors) > 0 {
+		util.InfoLogger.Printf("Draft %s has %d validation errors", draftID, len(fieldErrors))
+		util.RespondWithJson(writer, request, http.StatusUnprocessableEntity, ValidationErrorResponse{
+			Error:  "Draft cannot be published",
+			Fields: fieldErrors,
+		})
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(request.Context(), nil)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to create chirp.", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.db.WithTx(tx)
+
+	util.InfoLogger.Printf("Attempting to create chirp from draft: %s", draftID)
+	chirp, mentionedIDs, err := createChirp(request.Context(), queries, chirpParams, nil)
+	if err != nil {
+		respondWithCreateChirpError(writer, request, err)
+		return
+	}
+
+	// Deleting inside the transaction makes a concurrent second publish of
+	// the same draft fail instead of creating the chirp twice.
+	deleted, err := queries.DeleteDraft(request.Context(), database.DeleteDraftParams{ID: draftID, UserID: userID})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to delete draft.", err)
+		return
+	}
+	if deleted == 0 {
+		util.RespondWithError(writer, request, http.StatusNotFound, "Draft not found", fmt.Errorf("Draft %s was already published", draftID))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to create chirp.", err)
+		return
+	}
+
+	responseBody, err := cfg.announceChirp(request.Context(), chirp, mentionedIDs)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to load chirp details", err)
+		return
+	}
+
+	util.RespondWithJson(writer, request, http.StatusCreated, responseBody)
+	util.InfoLogger.Printf("Successfully published draft %s as chirp %s", draftID, chirp.ID)
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/auth"
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/notify"
+	"github.com/kwekkwekpatu/chirpy/internal/pagination"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+)
+
+func (cfg *ApiConfig) FollowHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling follow.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Reading request UserID.")
+	followeeID, err := uuid.Parse(request.PathValue("userID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid userID", err)
+		return
+	}
+
+	if followeeID == userID {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Users cannot follow themselves", fmt.Errorf("User %s tried to follow themselves", userID))
+		return
+	}
+
+	util.InfoLogger.Printf("Checking if user exists")
+	_, err = cfg.db.GetUserByID(request.Context(), followeeID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusNotFound, "Failed to find user", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Attempting to follow user %s for user %s", followeeID, userID)
+	created, err := cfg.db.CreateFollow(request.Context(), database.CreateFollowParams{FollowerID: userID, FolloweeID: followeeID})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to follow user.", err)
+		return
+	}
+
+	cfg.timelineFollowees.follow(userID, followeeID)
+
+	if created > 0 {
+		cfg.notify(request.Context(), notify.Event{
+			Type:      notify.TypeFollow,
+			Recipient: followeeID,
+			Actor:     uuid.NullUUID{UUID: userID, Valid: true},
+		})
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+	util.InfoLogger.Printf("Successfully followed user: %s", followeeID)
+}
+
+func (cfg *ApiConfig) UnfollowHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling unfollow.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Reading request UserID.")
+	followeeID, err := uuid.Parse(request.PathValue("userID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid userID", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Attempting to unfollow user %s for user %s", followeeID, userID)
+	err = cfg.db.DeleteFollow(request.Context(), database.DeleteFollowParams{FollowerID: userID, FolloweeID: followeeID})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to unfollow user.", err)
+		return
+	}
+	cfg.timelineFollowees.unfollow(userID, followeeID)
+
+	writer.WriteHeader(http.StatusNoContent)
+	util.InfoLogger.Printf("Successfully unfollowed user: %s", followeeID)
+}
+
+func (cfg *ApiConfig) TimelineHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling reading of timeline.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	pageParams, err := pagination.ParseParams(request.URL.Query())
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	cursorCreatedAt, cursorID := cursorArgs(pageParams.Cursor)
+
+	util.InfoLogger.Printf("Loading timeline for user: %s", userID)
+	chirpArray, err := cfg.db.ReadTimeline(request.Context(), database.ReadTimelineParams{
+		FollowerID:      userID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		PageLimit:       int32(pageParams.Limit + 1),
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read timeline", err)
+		return
+	}
+
+	responseBody := newChirpPage(chirpArray, pageParams.Limit)
+	err = cfg.hydrateChirps(request.Context(), responseBody.Chirps, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to load chirp details", err)
+		return
+	}
+
+	respondWithChirpPage(writer, request, responseBody)
+	util.InfoLogger.Printf("Successfully read timeline.")
+}
+
+// followeeRegistry holds the followees of every open timeline subscription,
+// so follows and unfollows reach websocket clients that are already
+// connected.
+type followeeRegistry struct {
+	mu   sync.Mutex
+	sets map[uuid.UUID]map[*followeeSet]struct{}
+}
+
+func newFolloweeRegistry() *followeeRegistry {
+	return &followeeRegistry{sets: map[uuid.UUID]map[*followeeSet]struct{}{}}
+}
+
+func (r *followeeRegistry) register(userID uuid.UUID) *followeeSet {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	set := &followeeSet{ids: map[uuid.UUID]struct{}{}, changes: map[uuid.UUID]bool{}}
+	if r.sets[userID] == nil {
+		r.sets[userID] = map[*followeeSet]struct{}{}
+	}
+	r.sets[userID][set] = struct{}{}
+	return set
+}
+
+func (r *followeeRegistry) unregister(userID uuid.UUID, set *followeeSet) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.sets[userID], set)
+	if len(r.sets[userID]) == 0 {
+		delete(r.sets, userID)
+	}
+}
+
+func (r *followeeRegistry) follow(userID, followeeID uuid.UUID) {
+	r.update(userID, followeeID, true)
+}
+
+func (r *followeeRegistry) unfollow(userID, followeeID uuid.UUID) {
+	r.update(userID, followeeID, false)
+}
+
+func (r *followeeRegistry) update(userID, followeeID uuid.UUID, following bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for set := range r.sets[userID] {
+		set.set(followeeID, following)
+	}
+}
+
+// followeeSet is the followees of one timeline subscription. Until it is
+// filled from the database it only records changes, which are applied on top
+// of what was read, as the read may or may not have seen them.
+type followeeSet struct {
+	mu      sync.RWMutex
+	filled  bool
+	ids     map[uuid.UUID]struct{}
+	changes map[uuid.UUID]bool
+}
+
+func (s *followeeSet) fill(followeeIDs []uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range followeeIDs {
+		s.ids[id] = struct{}{}
+	}
+	for id, following := range s.changes {
+		s.apply(id, following)
+	}
+	s.filled = true
+	s.changes = nil
+}
+
+func (s *followeeSet) set(followeeID uuid.UUID, following bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.filled {
+		s.changes[followeeID] = following
+		return
+	}
+	s.apply(followeeID, following)
+}
+
+// apply must be called with s.mu held.
+func (s *followeeSet) apply(followeeID uuid.UUID, following bool) {
+	if following {
+		s.ids[followeeID] = struct{}{}
+	} else {
+		delete(s.ids, followeeID)
+	}
+}
+
+func (s *followeeSet) contains(followeeID uuid.UUID) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.ids[followeeID]
+	return ok
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/entities"
+	"github.com/kwekkwekpatu/chirpy/internal/pagination"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+)
+
+const (
+	TrendingWindow   = 24 * time.Hour
+	TrendingHalfLife = 6 * time.Hour
+	TrendingLimit    = 10
+)
+
+type TrendingHashtag struct {
+	Tag        string  `json:"tag"`
+	UsageCount int64   `json:"usage_count"`
+	Score      float64 `json:"score"`
+}
+
+// tagChirp stores the hashtags found in the body of dbChirp. Existing tags of
+// the chirp are replaced so it can be used after an edit as well.
+func tagChirp(ctx context.Context, queries *database.Queries, dbChirp database.Chirp) error {
+	err := queries.DeleteChirpHashtags(ctx, dbChirp.ID)
+	if err != nil {
+		return err
+	}
+
+	tags := entities.Hashtags(dbChirp.Body)
+	if len(tags) == 0 {
+		return nil
+	}
+
+	util.InfoLogger.Printf("Tagging chirp %s with: %v", dbChirp.ID, tags)
+	return queries.TagChirp(ctx, database.TagChirpParams{
+		Tags:           tags,
+		ChirpID:        dbChirp.ID,
+		ChirpCreatedAt: dbChirp.CreatedAt,
+	})
+}
+
+func (cfg *ApiConfig) HashtagChirpsReadHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling reading of hashtag chirps.")
+
+	viewerID, err := cfg.optionalUserID(request)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	tag, ok := entities.NormalizeHashtag(request.PathValue("tag"))
+	if !ok {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid hashtag", fmt.Errorf("Invalid hashtag: %s", request.PathValue("tag")))
+		return
+	}
+
+	pageParams, err := pagination.ParseParams(request.URL.Query())
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	cursorCreatedAt, cursorID := cursorArgs(pageParams.Cursor)
+
+	util.InfoLogger.Printf("Loading chirps for hashtag: %s", tag)
+	chirpArray, err := cfg.db.ReadHashtagChirps(request.Context(), database.ReadHashtagChirpsParams{
+		Tag:             tag,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		PageLimit:       int32(pageParams.Limit + 1),
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read chirps", err)
+		return
+	}
+
+	responseBody := newChirpPage(chirpArray, pageParams.Limit)
+	err = cfg.hydrateChirps(request.Context(), responseBody.Chirps, viewerID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to load chirp details", err)
+		return
+	}
+
+	respondWithChirpPage(writer, request, responseBody)
+	util.InfoLogger.Printf("Successfully read hashtag chirps.")
+}
+
+func (cfg *ApiConfig) TrendingHashtagsHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling reading of trending hashtags.")
+
+	limit := TrendingLimit
+	if request.URL.Query().Get("limit") != "" {
+		var err error
+		limit, err = pagination.ParseLimit(request.URL.Query())
+		if err != nil {
+			util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	}
+
+	trending, err := cfg.db.ReadTrendingHashtags(request.Context(), database.ReadTrendingHashtagsParams{
+		HalfLifeSeconds: TrendingHalfLife.Seconds(),
+		WindowSeconds:   TrendingWindow.Seconds(),
+		PageLimit:       int32(limit),
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read trending hashtags", err)
+		return
+	}
+
+	responseBody := []TrendingHashtag{}
+	for _, hashtag := range trending {
+		responseBody = append(responseBody, TrendingHashtag{Tag: hashtag.Tag, UsageCount: hashtag.UsageCount, Score: hashtag.Score})
+	}
+
+	util.RespondWithJson(writer, request, http.StatusOK, responseBody)
+	util.InfoLogger.Printf("Successfully read trending hashtags.")
+}
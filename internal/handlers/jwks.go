@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+)
+
+// JWKSHandler publishes the public keys that verify access tokens, so other
+// services can check them without the signing key.
+func (cfg *ApiConfig) JWKSHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling reading of JWKS.")
+
+	// Verifiers refetch the set when they meet an unknown kid, and a new key
+	// is published well before it signs anything, so a short cache is enough.
+	writer.Header().Set("Cache-Control", "public, max-age=300")
+	util.RespondWithJson(writer, request, http.StatusOK, cfg.keyring.JWKS())
+}
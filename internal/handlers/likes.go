@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/auth"
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/notify"
+	"github.com/kwekkwekpatu/chirpy/internal/pagination"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+)
+
+func (cfg *ApiConfig) LikeHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling chirp like.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Reading request ChirpID.")
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid chirpID", err)
+		return
+	}
+
+	dbChirp, err := cfg.db.ReadChirp(request.Context(), chirpID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusNotFound, "chirp not found", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Attempting to like chirp %s for user %s", chirpID, userID)
+	created, err := cfg.db.CreateChirpLike(request.Context(), database.CreateChirpLikeParams{UserID: userID, ChirpID: chirpID})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to like chirp.", err)
+		return
+	}
+
+	if created > 0 && dbChirp.UserID.Valid && !cfg.likeAlreadyNotified(request.Context(), dbChirp.UserID.UUID, userID, chirpID) {
+		cfg.notify(request.Context(), notify.Event{
+			Type:      notify.TypeLike,
+			Recipient: dbChirp.UserID.UUID,
+			Actor:     uuid.NullUUID{UUID: userID, Valid: true},
+			ChirpID:   uuid.NullUUID{UUID: chirpID, Valid: true},
+		})
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+	util.InfoLogger.Printf("Successfully liked chirp: %s", chirpID)
+}
+
+func (cfg *ApiConfig) UnlikeHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling chirp unlike.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Reading request ChirpID.")
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid chirpID", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Attempting to unlike chirp %s for user %s", chirpID, userID)
+	err = cfg.db.DeleteChirpLike(request.Context(), database.DeleteChirpLikeParams{UserID: userID, ChirpID: chirpID})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to unlike chirp.", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+	util.InfoLogger.Printf("Successfully unliked chirp: %s", chirpID)
+}
+
+func (cfg *ApiConfig) UserLikesReadHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling reading of liked chirps.")
+
+	viewerID, err := cfg.optionalUserID(request)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Reading request UserID.")
+	userID, err := uuid.Parse(request.PathValue("userID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid userID", err)
+		return
+	}
+
+	pageParams, err := pagination.ParseParams(request.URL.Query())
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	cursorCreatedAt, cursorID := cursorArgs(pageParams.Cursor)
+
+	util.InfoLogger.Printf("Loading chirps liked by user: %s", userID)
+	likedChirps, err := cfg.db.ReadLikedChirps(request.Context(), database.ReadLikedChirpsParams{
+		UserID:          userID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		PageLimit:       int32(pageParams.Limit + 1),
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read liked chirps", err)
+		return
+	}
+
+	// The page is ordered by the time of the like, not of the chirp, so the
+	// cursor is built from liked_at.
+	responseBody := ChirpPage{Chirps: ChirpSlice{}}
+	if len(likedChirps) > pageParams.Limit {
+		likedChirps = likedChirps[:pageParams.Limit]
+		last := likedChirps[len(likedChirps)-1]
+		responseBody.NextCursor = pagination.EncodeCursor(pagination.Cursor{CreatedAt: last.LikedAt, ID: last.Chirp.ID})
+	}
+	for _, likedChirp := range likedChirps {
+		responseBody.Chirps = append(responseBody.Chirps, chirpFromDatabase(likedChirp.Chirp))
+	}
+
+	err = cfg.hydrateChirps(request.Context(), responseBody.Chirps, viewerID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to load chirp details", err)
+		return
+	}
+
+	respondWithChirpPage(writer, request, responseBody)
+	util.InfoLogger.Printf("Successfully read liked chirps.")
+}
+
+// attachLikeStats fills like_count and liked_by_me for a whole page of chirps
+// with a single query.
+func (cfg *ApiConfig) attachLikeStats(ctx context.Context, chirps ChirpSlice, viewerID uuid.NullUUID) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	chirpIDs := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+
+	stats, err := cfg.db.ReadChirpLikeStats(ctx, database.ReadChirpLikeStatsParams{ViewerID: viewerID, ChirpIds: chirpIDs})
+	if err != nil {
+		return err
+	}
+
+	statsByChirp := make(map[uuid.UUID]database.ReadChirpLikeStatsRow, len(stats))
+	for _, stat := range stats {
+		statsByChirp[stat.ChirpID] = stat
+	}
+
+	for i := range chirps {
+		stat := statsByChirp[chirps[i].ID]
+		chirps[i].LikeCount = stat.LikeCount
+		chirps[i].LikedByMe = stat.LikedByMe
+	}
+	return nil
+}
+
+// likeAlreadyNotified reports whether the author was already told about this
+// user's like on the chirp, so unliking and liking again does not notify twice.
+func (cfg *ApiConfig) likeAlreadyNotified(ctx context.Context, recipient, actor, chirpID uuid.UUID) bool {
+	exists, err := cfg.db.NotificationExists(ctx, database.NotificationExistsParams{
+		UserID:  recipient,
+		ActorID: uuid.NullUUID{UUID: actor, Valid: true},
+		Type:    notify.TypeLike,
+		ChirpID: uuid.NullUUID{UUID: chirpID, Valid: true},
+	})
+	if err != nil {
+		util.WarnLogger.Printf("Could not check existing like notification for chirp %s: %v", chirpID, err)
+		return false
+	}
+	return exists
+}
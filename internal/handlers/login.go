@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -21,6 +24,7 @@ type LoginResponse struct {
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 	Email        string    `json:"email"`
+	Username     string    `json:"username,omitempty"`
 	Token        string    `json:"token"`
 	RefreshToken string    `json:"refresh_token"`
 	ChirpyIsRed  bool      `json:"is_chirpy_red"`
@@ -55,26 +59,35 @@ func (cfg *ApiConfig) LoginHandler(writer http.ResponseWriter, request *http.Req
 		return
 	}
 
-	token, err := auth.MakeJWT(dbUser.ID, cfg.jwtSecret, time.Hour)
-	if err != nil {
-		util.RespondWithError(writer, request, http.StatusUnauthorized, "Failed to create token.", err)
+	if dbUser.SuspendedAt.Valid {
+		util.RespondWithError(writer, request, http.StatusForbidden, "Account is suspended", fmt.Errorf("User %s was suspended at %s", dbUser.ID, dbUser.SuspendedAt.Time))
 		return
 	}
 
-	refreshDuration := time.Duration(RefreshExpirationDuration) * time.Second
-	refreshToken, err := auth.MakeRefreshToken()
-	if err != nil {
-		util.RespondWithError(writer, request, http.StatusUnauthorized, "Failed to create refresh_token.", err)
+	credential, err := cfg.db.ReadTOTPCredential(request.Context(), dbUser.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, util.InternalServerError, err)
+		return
+	}
+	if err == nil && credential.ConfirmedAt.Valid {
+		cfg.startMFAChallenge(writer, request, dbUser.ID)
 		return
 	}
 
-	refreshParams := database.CreateRefreshTokenParams{
-		Token:     refreshToken,
-		ExpiresAt: time.Now().Add(refreshDuration),
-		UserID:    dbUser.ID,
+	cfg.completeLogin(writer, request, dbUser)
+}
+
+// completeLogin issues the tokens of a user who passed every login step.
+func (cfg *ApiConfig) completeLogin(writer http.ResponseWriter, request *http.Request, dbUser database.User) {
+	// Every login starts a new refresh token family, which is the session.
+	sessionID := uuid.New()
+	token, err := cfg.keyring.MakeSessionJWT(dbUser.ID, sessionID, time.Hour)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Failed to create token.", err)
+		return
 	}
 
-	_, err = cfg.db.CreateRefreshToken(request.Context(), refreshParams)
+	refreshToken, err := issueRefreshToken(request, cfg.db, dbUser.ID, sessionID)
 	if err != nil {
 		util.RespondWithError(writer, request, http.StatusUnauthorized, "Failed to store refresh_token.", err)
 		return
@@ -82,7 +95,7 @@ func (cfg *ApiConfig) LoginHandler(writer http.ResponseWriter, request *http.Req
 
 	util.InfoLogger.Printf("Generating response body from login.")
 	responseBody := LoginResponse{ID: dbUser.ID, CreatedAt: dbUser.CreatedAt,
-		UpdatedAt: dbUser.UpdatedAt, Email: dbUser.Email, Token: token,
+		UpdatedAt: dbUser.UpdatedAt, Email: dbUser.Email, Username: dbUser.Username.String, Token: token,
 		RefreshToken: refreshToken, ChirpyIsRed: dbUser.IsChirpyRed}
 
 	util.RespondWithJson(writer, request, http.StatusOK, responseBody)
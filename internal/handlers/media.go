@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/auth"
+	"github.com/kwekkwekpatu/chirpy/internal/authz"
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/media"
+	"github.com/kwekkwekpatu/chirpy/internal/storage"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+)
+
+const (
+	MaxChirpMedia = 4
+	// How long caches may keep media, which stops being served once its
+	// chirp is deleted or hidden.
+	MediaMaxAge = 5 * time.Minute
+	// Uploads that no chirp or scheduled chirp uses are removed after this.
+	StaleMediaAge = 24 * time.Hour
+	// Leaves room for the multipart framing around the file itself.
+	MaxMediaRequestSize = media.MaxUploadSize + 64*1024
+)
+
+var errMediaNotFound = errors.New("Media not found")
+
+type Media struct {
+	ID           uuid.UUID `json:"id"`
+	ContentType  string    `json:"content_type"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+}
+
+func mediaFromDatabase(dbMedia database.Medium) Media {
+	return Media{
+		ID:           dbMedia.ID,
+		ContentType:  dbMedia.ContentType,
+		Width:        dbMedia.Width,
+		Height:       dbMedia.Height,
+		URL:          "/api/media/" + dbMedia.ID.String(),
+		ThumbnailURL: "/api/media/" + dbMedia.ID.String() + "/thumbnail",
+	}
+}
+
+func mediaExtension(contentType string) string {
+	switch contentType {
+	case media.ContentTypeJPEG:
+		return "jpg"
+	case media.ContentTypeGIF:
+		return "gif"
+	default:
+		return "png"
+	}
+}
+
+func (cfg *ApiConfig) MediaUploadHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling media upload.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Loading uploaded file.")
+	request.Body = http.MaxBytesReader(writer, request.Body, MaxMediaRequestSize)
+	file, _, err := request.FormFile("file")
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		util.RespondWithError(writer, request, http.StatusRequestEntityTooLarge, "Upload is too large", err)
+		return
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Missing file", err)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, media.MaxUploadSize+1))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Failed to read file", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Processing uploaded image for user: %s", userID)
+	processed, err := media.Process(data)
+	if errors.Is(err, media.ErrTooLarge) {
+		util.RespondWithError(writer, request, http.StatusRequestEntityTooLarge, err.Error(), err)
+		return
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Unsupported or invalid image", err)
+		return
+	}
+
+	mediaID := uuid.New()
+	storageKey := fmt.Sprintf("media/%s/original.%s", mediaID, mediaExtension(processed.Original.ContentType))
+	thumbnailKey := fmt.Sprintf("media/%s/thumbnail.%s", mediaID, mediaExtension(processed.Thumbnail.ContentType))
+
+	util.InfoLogger.Printf("Storing media: %s", mediaID)
+	err = cfg.storage.Put(request.Context(), storageKey, processed.Original.Data, processed.Original.ContentType)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to store media.", err)
+		return
+	}
+	err = cfg.storage.Put(request.Context(), thumbnailKey, processed.Thumbnail.Data, processed.Thumbnail.ContentType)
+	if err != nil {
+		cfg.deleteStoredObjects(request.Context(), storageKey)
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to store media.", err)
+		return
+	}
+
+	dbMedia, err := cfg.db.CreateMedia(request.Context(), database.CreateMediaParams{
+		ID:                   mediaID,
+		UserID:               userID,
+		ContentType:          processed.Original.ContentType,
+		Width:                int32(processed.Original.Width),
+		Height:               int32(processed.Original.Height),
+		StorageKey:           storageKey,
+		ThumbnailKey:         thumbnailKey,
+		ThumbnailContentType: processed.Thumbnail.ContentType,
+	})
+	if err != nil {
+		cfg.deleteStoredObjects(request.Context(), storageKey, thumbnailKey)
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to create media.", err)
+		return
+	}
+
+	util.RespondWithJson(writer, request, http.StatusCreated, mediaFromDatabase(dbMedia))
+	util.InfoLogger.Printf("Successfully uploaded media: %s", mediaID)
+}
+
+func (cfg *ApiConfig) MediaReadHandler(writer http.ResponseWriter, request *http.Request) {
+	cfg.serveMedia(writer, request, false)
+}
+
+func (cfg *ApiConfig) MediaThumbnailReadHandler(writer http.ResponseWriter, request *http.Request) {
+	cfg.serveMedia(writer, request, true)
+}
+
+func (cfg *ApiConfig) serveMedia(writer http.ResponseWriter, request *http.Request, thumbnail bool) {
+	util.InfoLogger.Printf("Handling reading of media.")
+
+	util.InfoLogger.Printf("Reading request MediaID.")
+	mediaID, err := uuid.Parse(request.PathValue("mediaID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid mediaID", err)
+		return
+	}
+
+	dbMedia, err := cfg.db.ReadMedia(request.Context(), mediaID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusNotFound, "Media not found", err)
+		return
+	}
+
+	// Objects never change, a new upload always gets a new id. Whether they
+	// may be seen does once they are attached, so caches only keep them
+	// briefly.
+	cacheControl := fmt.Sprintf("public, max-age=%d", int(MediaMaxAge.Seconds()))
+	if dbMedia.ChirpID.Valid {
+		viewerID, err := cfg.optionalUserID(request)
+		if err != nil {
+			util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+			return
+		}
+		visible, restricted, err := cfg.canReadChirpMedia(request.Context(), dbMedia.ChirpID.UUID, viewerID)
+		if err != nil {
+			util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read media.", err)
+			return
+		}
+		if !visible {
+			util.RespondWithError(writer, request, http.StatusNotFound, "Media not found", fmt.Errorf("Chirp %s of media %s is deleted", dbMedia.ChirpID.UUID, mediaID))
+			return
+		}
+		if restricted {
+			cacheControl = "private, no-store"
+		}
+	}
+
+	key, contentType := dbMedia.StorageKey, dbMedia.ContentType
+	if thumbnail {
+		key, contentType = dbMedia.ThumbnailKey, dbMedia.ThumbnailContentType
+	}
+
+	object, err := cfg.storage.Get(request.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		util.RespondWithError(writer, request, http.StatusNotFound, "Media not found", err)
+		return
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read media.", err)
+		return
+	}
+	defer object.Close()
+
+	writer.Header().Set("Content-Type", contentType)
+	writer.Header().Set("Cache-Control", cacheControl)
+	writer.Header().Set("X-Content-Type-Options", "nosniff")
+	writer.WriteHeader(http.StatusOK)
+	_, err = io.Copy(writer, object)
+	if err != nil {
+		util.ErrorLogger.Println(err)
+	}
+}
+
+// canReadChirpMedia reports whether the viewer may see the media of a chirp.
+// Media of deleted and hidden chirps stays visible to the chirp's owner and
+// to moderators until the purge; restricted reports that only they see it.
+func (cfg *ApiConfig) canReadChirpMedia(ctx context.Context, chirpID uuid.UUID, viewerID uuid.NullUUID) (visible bool, restricted bool, err error) {
+	dbChirp, err := cfg.db.ReadChirpIncludingDeleted(ctx, chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	if !dbChirp.DeletedAt.Valid {
+		return true, false, nil
+	}
+
+	if viewerID.Valid && dbChirp.UserID == viewerID {
+		return true, true, nil
+	}
+	permissions, err := cfg.userPermissions(ctx, viewerID)
+	if err != nil {
+		return false, false, err
+	}
+	return permissions.Has(authz.ReadDeletedChirps), true, nil
+}
+
+// attachMedia embeds the media of every chirp in chirps with a single query.
+func (cfg *ApiConfig) attachMedia(ctx context.Context, chirps ChirpSlice) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+	chirpIDs := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+
+	dbMedia, err := cfg.db.ReadChirpMedia(ctx, chirpIDs)
+	if err != nil {
+		return err
+	}
+
+	mediaByChirp := map[uuid.UUID][]Media{}
+	for _, medium := range dbMedia {
+		mediaByChirp[medium.ChirpID.UUID] = append(mediaByChirp[medium.ChirpID.UUID], mediaFromDatabase(medium))
+	}
+	for i := range chirps {
+		if chirpMedia, ok := mediaByChirp[chirps[i].ID]; ok {
+			chirps[i].Media = chirpMedia
+		}
+	}
+	return nil
+}
+
+// validateMediaIDs checks the media_ids of a new chirp before anything is
+// written.
+func validateMediaIDs(mediaIDs []uuid.UUID) error {
+	if len(mediaIDs) > MaxChirpMedia {
+		return fmt.Errorf("A chirp can have at most %d media", MaxChirpMedia)
+	}
+	seen := map[uuid.UUID]bool{}
+	for _, mediaID := range mediaIDs {
+		if seen[mediaID] {
+			return fmt.Errorf("Duplicate media_id")
+		}
+		seen[mediaID] = true
+	}
+	return nil
+}
+
+// purgeStaleMedia removes one batch of uploads that were never attached and
+// afterwards their stored files.
+func (cfg *ApiConfig) purgeStaleMedia(ctx context.Context) (int, error) {
+	dbMedia, err := cfg.db.DeleteStaleMedia(ctx, database.DeleteStaleMediaParams{
+		MaxAgeSeconds: StaleMediaAge.Seconds(),
+		PageLimit:     ChirpPurgeBatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, medium := range dbMedia {
+		cfg.deleteStoredObjects(ctx, medium.StorageKey, medium.ThumbnailKey)
+	}
+	return len(dbMedia), nil
+}
+
+// deleteStoredObjects removes objects whose rows are gone. Leftovers only
+// waste space, so failures are logged.
+func (cfg *ApiConfig) deleteStoredObjects(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		err := cfg.storage.Delete(ctx, key)
+		if err != nil {
+			util.ErrorLogger.Printf("Failed to delete stored object %s: %s", key, err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/entities"
+	"github.com/kwekkwekpatu/chirpy/internal/notify"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+)
+
+type Mention struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+}
+
+// mentionUsers stores the users mentioned in the body of dbChirp and returns
+// the ones that were not mentioned before, so they can be notified. Mentions
+// that were removed by an edit are dropped.
+func mentionUsers(ctx context.Context, queries *database.Queries, dbChirp database.Chirp) ([]uuid.UUID, error) {
+	usernames := entities.Mentions(dbChirp.Body)
+
+	err := queries.DeleteStaleChirpMentions(ctx, database.DeleteStaleChirpMentionsParams{ChirpID: dbChirp.ID, Usernames: usernames})
+	if err != nil {
+		return nil, err
+	}
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+
+	util.InfoLogger.Printf("Resolving mentions of chirp %s: %v", dbChirp.ID, usernames)
+	return queries.CreateChirpMentions(ctx, database.CreateChirpMentionsParams{ChirpID: dbChirp.ID, Usernames: usernames})
+}
+
+func (cfg *ApiConfig) notifyMentions(ctx context.Context, dbChirp database.Chirp, mentionedIDs []uuid.UUID) {
+	for _, mentionedID := range mentionedIDs {
+		cfg.notify(ctx, notify.Event{
+			Type:      notify.TypeMention,
+			Recipient: mentionedID,
+			Actor:     dbChirp.UserID,
+			ChirpID:   uuid.NullUUID{UUID: dbChirp.ID, Valid: true},
+		})
+	}
+}
+
+// attachMentions loads the resolved mentions of a page of chirps with a single
+// query.
+func (cfg *ApiConfig) attachMentions(ctx context.Context, chirps ChirpSlice) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	chirpIDs := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+
+	mentions, err := cfg.db.ReadChirpMentions(ctx, chirpIDs)
+	if err != nil {
+		return err
+	}
+
+	mentionsByChirp := make(map[uuid.UUID][]Mention)
+	for _, mention := range mentions {
+		mentionsByChirp[mention.ChirpID] = append(mentionsByChirp[mention.ChirpID], Mention{UserID: mention.UserID, Username: mention.Username.String})
+	}
+
+	for i := range chirps {
+		if chirpMentions, ok := mentionsByChirp[chirps[i].ID]; ok {
+			chirps[i].Mentions = chirpMentions
+		}
+	}
+	return nil
+}
@@ -0,0 +1,580 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/auth"
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/mfa"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+)
+
+const (
+	TOTPIssuer = "Chirpy"
+
+	// A challenge allows a few typos and then the password has to be
+	// entered again, so codes cannot be guessed one login at a time.
+	MFAChallengeDuration = 5 * time.Minute
+	MaxMFAAttempts       = 5
+	MFALockoutDuration   = 15 * time.Minute
+)
+
+type MFAChallengeResponse struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type MFAStatus struct {
+	TOTPEnabled            bool  `json:"totp_enabled"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type mfaCodeParameters struct {
+	Code string `json:"code"`
+}
+
+func decodeMFACode(request *http.Request) (string, error) {
+	decoder := json.NewDecoder(request.Body)
+	params := mfaCodeParameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		return "", fmt.Errorf("Error decoding parameters.")
+	}
+	if params.Code == "" {
+		return "", fmt.Errorf("Code is required")
+	}
+	return params.Code, nil
+}
+
+// startMFAChallenge answers a correct password of a user with two-factor
+// authentication by a challenge instead of tokens.
+func (cfg *ApiConfig) startMFAChallenge(writer http.ResponseWriter, request *http.Request, userID uuid.UUID) {
+	util.InfoLogger.Printf("Starting MFA challenge for user: %s", userID)
+
+	err := cfg.db.DeleteExpiredMFAChallenges(request.Context(), time.Now().UTC())
+	if err != nil {
+		util.ErrorLogger.Printf("Failed to delete expired MFA challenges: %s", err)
+	}
+
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to create MFA challenge.", err)
+		return
+	}
+
+	challenge, err := cfg.db.CreateMFAChallenge(request.Context(), database.CreateMFAChallengeParams{
+		Token:     token,
+		UserID:    userID,
+		ExpiresAt: time.Now().UTC().Add(MFAChallengeDuration),
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to create MFA challenge.", err)
+		return
+	}
+
+	util.RespondWithJson(writer, request, http.StatusOK, MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    challenge.Token,
+		ExpiresAt:   challenge.ExpiresAt,
+	})
+}
+
+// errSecondFactorLocked is returned while a user is locked out after too
+// many wrong codes.
+var errSecondFactorLocked = errors.New("Too many wrong codes, try again later")
+
+// verifySecondFactor checks a TOTP code or a recovery code and uses it up.
+// Every MaxMFAAttempts wrong codes in a row lock the user out for
+// MFALockoutDuration, whichever endpoint they were entered at. It must run
+// inside a transaction that is committed whether or not the code was right,
+// so that failures are counted.
+func verifySecondFactor(ctx context.Context, queries *database.Queries, userID uuid.UUID, code string) (bool, error) {
+	credential, err := queries.ReadTOTPCredentialForUpdate(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !credential.ConfirmedAt.Valid {
+		return false, nil
+	}
+	now := time.Now().UTC()
+	if credential.LockedUntil.Valid && now.Before(credential.LockedUntil.Time) {
+		return false, errSecondFactorLocked
+	}
+
+	ok, err := checkSecondFactor(ctx, queries, credential, code, now)
+	if err != nil {
+		return false, err
+	}
+
+	attempts := database.SetTOTPFailedAttemptsParams{UserID: userID}
+	if !ok {
+		attempts.FailedAttempts = credential.FailedAttempts + 1
+		if attempts.FailedAttempts >= MaxMFAAttempts {
+			util.WarnLogger.Printf("Too many wrong codes for user %s, locking second factor", userID)
+			attempts.FailedAttempts = 0
+			attempts.LockedUntil = sql.NullTime{Time: now.Add(MFALockoutDuration), Valid: true}
+		}
+	}
+	if !ok || credential.FailedAttempts > 0 || credential.LockedUntil.Valid {
+		err = queries.SetTOTPFailedAttempts(ctx, attempts)
+		if err != nil {
+			return false, err
+		}
+	}
+	return ok, nil
+}
+
+// rejectSecondFactor answers a code that verifySecondFactor did not accept.
+// Wrong codes are committed so that they count towards the lockout.
+func rejectSecondFactor(writer http.ResponseWriter, request *http.Request, tx *sql.Tx, err error, invalidMessage, failureMessage string) {
+	if errors.Is(err, errSecondFactorLocked) {
+		util.RespondWithError(writer, request, http.StatusTooManyRequests, err.Error(), err)
+		return
+	}
+	if err == nil {
+		err = tx.Commit()
+		if err == nil {
+			util.RespondWithError(writer, request, http.StatusUnauthorized, invalidMessage, nil)
+			return
+		}
+	}
+	util.RespondWithError(writer, request, http.StatusInternalServerError, failureMessage, err)
+}
+
+func checkSecondFactor(ctx context.Context, queries *database.Queries, credential database.TotpCredential, code string, now time.Time) (bool, error) {
+	if !mfa.IsCode(code) {
+		rows, err := queries.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			UserID:   credential.UserID,
+			CodeHash: mfa.HashRecoveryCode(code),
+		})
+		return rows > 0, err
+	}
+
+	step, ok := mfa.Validate(credential.Secret, code, now)
+	if !ok {
+		return false, nil
+	}
+
+	// A code that was already used, or one older than the last used code,
+	// is a replay.
+	rows, err := queries.UseTOTPStep(ctx, database.UseTOTPStepParams{UserID: credential.UserID, LastUsedStep: step})
+	return rows > 0, err
+}
+
+// replaceRecoveryCodes invalidates the user's recovery codes and returns new
+// ones. Only their hashes are stored, so this is the only time they are seen.
+func replaceRecoveryCodes(ctx context.Context, queries *database.Queries, userID uuid.UUID) ([]string, error) {
+	codes, err := mfa.GenerateRecoveryCodes(mfa.RecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	err = queries.DeleteRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		err = queries.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: mfa.HashRecoveryCode(code),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// LoginMFAHandler is the second login step. It trades the challenge token
+// from LoginHandler and a TOTP or recovery code for the real tokens.
+func (cfg *ApiConfig) LoginMFAHandler(writer http.ResponseWriter, request *http.Request) {
+	type parameters struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}
+
+	util.InfoLogger.Printf("Handling MFA login.")
+
+	decoder := json.NewDecoder(request.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Error decoding parameters.", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(request.Context(), nil)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, util.InternalServerError, err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.db.WithTx(tx)
+
+	challenge, err := queries.ReadMFAChallengeForUpdate(request.Context(), params.MFAToken)
+	if errors.Is(err, sql.ErrNoRows) {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "MFA token is invalid or expired", err)
+		return
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, util.InternalServerError, err)
+		return
+	}
+	if !time.Now().UTC().Before(challenge.ExpiresAt) {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "MFA token is invalid or expired", fmt.Errorf("MFA challenge expired at %s", challenge.ExpiresAt))
+		return
+	}
+
+	ok, err := verifySecondFactor(request.Context(), queries, challenge.UserID, params.Code)
+	if errors.Is(err, errSecondFactorLocked) {
+		util.RespondWithError(writer, request, http.StatusTooManyRequests, err.Error(), err)
+		return
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, util.InternalServerError, err)
+		return
+	}
+	if !ok {
+		attempts, err := queries.IncrementMFAChallengeAttempts(request.Context(), challenge.Token)
+		if err == nil && attempts >= MaxMFAAttempts {
+			util.WarnLogger.Printf("Too many MFA attempts for user %s, dropping challenge", challenge.UserID)
+			err = queries.DeleteMFAChallenge(request.Context(), challenge.Token)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			util.RespondWithError(writer, request, http.StatusInternalServerError, util.InternalServerError, err)
+			return
+		}
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Invalid code", nil)
+		return
+	}
+
+	err = queries.DeleteMFAChallenge(request.Context(), challenge.Token)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, util.InternalServerError, err)
+		return
+	}
+	dbUser, err := queries.GetUserByID(request.Context(), challenge.UserID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, util.InternalServerError, err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, util.InternalServerError, err)
+		return
+	}
+
+	// The user may have been suspended since they entered their password.
+	if dbUser.SuspendedAt.Valid {
+		util.RespondWithError(writer, request, http.StatusForbidden, "Account is suspended", fmt.Errorf("User %s was suspended at %s", dbUser.ID, dbUser.SuspendedAt.Time))
+		return
+	}
+
+	cfg.completeLogin(writer, request, dbUser)
+}
+
+func (cfg *ApiConfig) MFAStatusHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling reading of MFA status.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	responseBody := MFAStatus{}
+	credential, err := cfg.db.ReadTOTPCredential(request.Context(), userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read MFA status", err)
+		return
+	}
+	responseBody.TOTPEnabled = err == nil && credential.ConfirmedAt.Valid
+
+	if responseBody.TOTPEnabled {
+		responseBody.RecoveryCodesRemaining, err = cfg.db.CountUnusedRecoveryCodes(request.Context(), userID)
+		if err != nil {
+			util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read MFA status", err)
+			return
+		}
+	}
+
+	util.RespondWithJson(writer, request, http.StatusOK, responseBody)
+}
+
+// TOTPEnrollHandler starts enrolment with a new secret. Two-factor
+// authentication is only enabled once TOTPConfirmHandler has seen a code
+// from it.
+func (cfg *ApiConfig) TOTPEnrollHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling TOTP enrolment.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	dbUser, err := cfg.db.GetUserByID(request.Context(), userID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to start enrolment.", err)
+		return
+	}
+
+	secret, err := mfa.GenerateSecret()
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to start enrolment.", err)
+		return
+	}
+
+	// Starting over replaces a pending secret but never a confirmed one.
+	_, err = cfg.db.UpsertPendingTOTPCredential(request.Context(), database.UpsertPendingTOTPCredentialParams{
+		UserID: userID,
+		Secret: secret,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		util.RespondWithError(writer, request, http.StatusConflict, "Two-factor authentication is already enabled", err)
+		return
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to start enrolment.", err)
+		return
+	}
+
+	util.RespondWithJson(writer, request, http.StatusCreated, TOTPEnrollment{
+		Secret: secret,
+		URI:    mfa.URI(TOTPIssuer, dbUser.Email, secret),
+	})
+	util.InfoLogger.Printf("Successfully started TOTP enrolment for user: %s", userID)
+}
+
+// TOTPConfirmHandler enables two-factor authentication once the user proves
+// their authenticator works, and hands out the recovery codes.
+func (cfg *ApiConfig) TOTPConfirmHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling TOTP confirmation.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	code, err := decodeMFACode(request)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(request.Context(), nil)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to confirm enrolment.", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.db.WithTx(tx)
+
+	credential, err := queries.ReadTOTPCredentialForUpdate(request.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		util.RespondWithError(writer, request, http.StatusNotFound, "No two-factor enrolment in progress", err)
+		return
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to confirm enrolment.", err)
+		return
+	}
+	if credential.ConfirmedAt.Valid {
+		util.RespondWithError(writer, request, http.StatusConflict, "Two-factor authentication is already enabled", nil)
+		return
+	}
+
+	step, ok := mfa.Validate(credential.Secret, code, time.Now())
+	if !ok {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid code", nil)
+		return
+	}
+
+	err = queries.ConfirmTOTPCredential(request.Context(), database.ConfirmTOTPCredentialParams{
+		UserID:       userID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to confirm enrolment.", err)
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(request.Context(), queries, userID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to create recovery codes.", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to confirm enrolment.", err)
+		return
+	}
+
+	util.RespondWithJson(writer, request, http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+	util.InfoLogger.Printf("Successfully enabled TOTP for user: %s", userID)
+}
+
+// TOTPDisableHandler turns two-factor authentication off. A pending
+// enrolment is simply dropped; an enabled one needs a code first.
+func (cfg *ApiConfig) TOTPDisableHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling disabling of TOTP.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(request.Context(), nil)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to disable two-factor authentication.", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.db.WithTx(tx)
+
+	credential, err := queries.ReadTOTPCredentialForUpdate(request.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		util.RespondWithError(writer, request, http.StatusNotFound, "Two-factor authentication is not enabled", err)
+		return
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to disable two-factor authentication.", err)
+		return
+	}
+
+	if credential.ConfirmedAt.Valid {
+		code, err := decodeMFACode(request)
+		if err != nil {
+			util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		ok, err := verifySecondFactor(request.Context(), queries, userID, code)
+		if err != nil || !ok {
+			rejectSecondFactor(writer, request, tx, err, "Invalid code", "Failed to disable two-factor authentication.")
+			return
+		}
+	}
+
+	err = queries.DeleteTOTPCredential(request.Context(), userID)
+	if err == nil {
+		err = queries.DeleteRecoveryCodes(request.Context(), userID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to disable two-factor authentication.", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+	util.InfoLogger.Printf("Successfully disabled TOTP for user: %s", userID)
+}
+
+// RecoveryCodesHandler replaces the user's recovery codes, for when they run
+// low or may have been seen by someone else.
+func (cfg *ApiConfig) RecoveryCodesHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling regeneration of recovery codes.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	code, err := decodeMFACode(request)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(request.Context(), nil)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to create recovery codes.", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.db.WithTx(tx)
+
+	ok, err := verifySecondFactor(request.Context(), queries, userID, code)
+	if err != nil || !ok {
+		rejectSecondFactor(writer, request, tx, err, "Invalid code", "Failed to create recovery codes.")
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(request.Context(), queries, userID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to create recovery codes.", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to create recovery codes.", err)
+		return
+	}
+
+	util.RespondWithJson(writer, request, http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+	util.InfoLogger.Printf("Successfully created recovery codes for user: %s", userID)
+}
@@ -4,6 +4,10 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/auth"
+	"github.com/kwekkwekpatu/chirpy/internal/authz"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
 	_ "github.com/lib/pq"
 )
 
@@ -31,3 +35,37 @@ func (cfg *ApiConfig) MiddlewareMetricsReset(writer http.ResponseWriter, request
 	cfg.fileserverHits = 0
 	writer.WriteHeader(http.StatusOK)
 }
+
+// MiddlewareRequirePermission only lets a request through when the subject of
+// its JWT has permission. The handler finds the user and their permissions
+// with authz.FromContext.
+func (cfg *ApiConfig) MiddlewareRequirePermission(permission authz.Permission, next http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		util.InfoLogger.Printf("Extracting and validating JWT token")
+		tokenString, err := auth.GetBearerToken(request.Header)
+		if err != nil {
+			util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+			return
+		}
+
+		userID, err := cfg.keyring.ValidateJWT(tokenString)
+		if err != nil {
+			util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+			return
+		}
+
+		util.InfoLogger.Printf("Checking permission %s for user: %s", permission, userID)
+		permissions, err := cfg.userPermissions(request.Context(), uuid.NullUUID{UUID: userID, Valid: true})
+		if err != nil {
+			util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read permissions", err)
+			return
+		}
+		if !permissions.Has(permission) {
+			util.RespondWithError(writer, request, http.StatusForbidden, "Missing permission "+string(permission), fmt.Errorf("User %s lacks %s", userID, permission))
+			return
+		}
+
+		principal := authz.Principal{UserID: userID, Permissions: permissions}
+		next(writer, request.WithContext(authz.NewContext(request.Context(), principal)))
+	}
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/authz"
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/moderation"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+)
+
+// Every replica reloads the rules on this interval, so rule changes made
+// through another replica or in the rules file are picked up without a
+// restart. POST /admin/moderation/reload applies them right away.
+const ModerationReloadInterval = time.Minute
+
+type ModerationRule struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Kind      string    `json:"kind"`
+	Action    string    `json:"action"`
+	Pattern   string    `json:"pattern"`
+}
+
+// reloadModerationRules rebuilds the moderation filters from the rules file,
+// or the default rules when there is none, and the rules in the database.
+// The current filters stay in place if any rule is invalid.
+func (cfg *ApiConfig) reloadModerationRules(ctx context.Context) (int, error) {
+	rules := moderation.DefaultRules()
+	if cfg.moderationRulesFile != "" {
+		fileRules, err := moderation.LoadRules(cfg.moderationRulesFile)
+		if err != nil {
+			return 0, err
+		}
+		rules = fileRules
+	}
+
+	dbRules, err := cfg.db.ReadModerationRules(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, dbRule := range dbRules {
+		rules = append(rules, moderation.Rule{Kind: dbRule.Kind, Action: moderation.Action(dbRule.Action), Pattern: dbRule.Pattern})
+	}
+
+	filters, err := moderation.NewFilters(rules)
+	if err != nil {
+		return 0, err
+	}
+	cfg.moderation.SetFilters(filters...)
+	return len(rules), nil
+}
+
+// RunModerationRuleReloader reloads the moderation rules until ctx is done.
+func (cfg *ApiConfig) RunModerationRuleReloader(ctx context.Context) {
+	util.InfoLogger.Printf("Starting moderation rule reloader.")
+	ticker := time.NewTicker(ModerationReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		_, err := cfg.reloadModerationRules(ctx)
+		if err != nil {
+			util.ErrorLogger.Printf("Failed to reload moderation rules: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			util.InfoLogger.Printf("Stopping moderation rule reloader.")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// flagChirp reports a committed chirp for review when its body matches a
+// flag rule. Like notifications it only logs failures.
+func (cfg *ApiConfig) flagChirp(ctx context.Context, chirp database.Chirp) {
+	result := cfg.moderation.Moderate(chirp.Body)
+	if !result.Flagged() || !chirp.UserID.Valid {
+		return
+	}
+
+	rules := []string{}
+	for _, match := range result.Matches {
+		if match.Action == moderation.ActionFlag {
+			rules = append(rules, match.Filter+" "+match.Rule)
+		}
+	}
+
+	util.InfoLogger.Printf("Flagging chirp %s for review: %v", chirp.ID, rules)
+	// Edits that still match are not queued again.
+	err := cfg.db.CreateAutomatedReport(ctx, database.CreateAutomatedReportParams{
+		ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		UserID:  chirp.UserID.UUID,
+		Details: strings.Join(rules, ", "),
+	})
+	if err != nil {
+		util.ErrorLogger.Printf("Failed to flag chirp %s: %s", chirp.ID, err)
+	}
+}
+
+func (cfg *ApiConfig) ModerationRulesReadHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling reading of moderation rules.")
+
+	dbRules, err := cfg.db.ReadModerationRules(request.Context())
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read moderation rules", err)
+		return
+	}
+
+	responseBody := []ModerationRule{}
+	for _, dbRule := range dbRules {
+		responseBody = append(responseBody, ModerationRule(dbRule))
+	}
+
+	util.RespondWithJson(writer, request, http.StatusOK, responseBody)
+	util.InfoLogger.Printf("Successfully read moderation rules.")
+}
+
+func (cfg *ApiConfig) ModerationRuleHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling moderation rule creation.")
+
+	principal, _ := authz.FromContext(request.Context())
+	userID := principal.UserID
+
+	type parameters struct {
+		Kind    string `json:"kind"`
+		Action  string `json:"action"`
+		Pattern string `json:"pattern"`
+	}
+
+	util.InfoLogger.Printf("Loading request parameter.")
+	decoder := json.NewDecoder(request.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Error decoding parameters.", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Validating moderation rule.")
+	action, err := moderation.ParseAction(params.Action)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	_, err = moderation.NewFilters([]moderation.Rule{{Kind: params.Kind, Action: action, Pattern: params.Pattern}})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(request.Context(), nil)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to create moderation rule.", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.db.WithTx(tx)
+
+	util.InfoLogger.Printf("User %s is adding a %s rule", userID, params.Kind)
+	dbRule, err := queries.CreateModerationRule(request.Context(), database.CreateModerationRuleParams{
+		Kind:    params.Kind,
+		Action:  params.Action,
+		Pattern: params.Pattern,
+	})
+	if isUniqueViolation(err) {
+		util.RespondWithError(writer, request, http.StatusConflict, "Moderation rule already exists", err)
+		return
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to create moderation rule.", err)
+		return
+	}
+
+	err = queries.CreateModerationAction(request.Context(), database.CreateModerationActionParams{
+		ModeratorID: uuid.NullUUID{UUID: userID, Valid: true},
+		Action:      ModerationActionAddRule,
+		Note:        fmt.Sprintf("%s %s %s", dbRule.Kind, dbRule.Action, dbRule.Pattern),
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to record moderation action.", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to create moderation rule.", err)
+		return
+	}
+
+	_, err = cfg.reloadModerationRules(request.Context())
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to reload moderation rules", err)
+		return
+	}
+
+	util.RespondWithJson(writer, request, http.StatusCreated, ModerationRule(dbRule))
+	util.InfoLogger.Printf("Successfully created moderation rule: %s", dbRule.ID)
+}
+
+func (cfg *ApiConfig) ModerationRuleDeleteHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling moderation rule deletion.")
+
+	principal, _ := authz.FromContext(request.Context())
+	userID := principal.UserID
+
+	util.InfoLogger.Printf("Reading request RuleID.")
+	ruleID, err := uuid.Parse(request.PathValue("ruleID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid ruleID", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(request.Context(), nil)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to delete moderation rule.", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.db.WithTx(tx)
+
+	util.InfoLogger.Printf("User %s is deleting moderation rule %s", userID, ruleID)
+	dbRule, err := queries.DeleteModerationRule(request.Context(), ruleID)
+	if errors.Is(err, sql.ErrNoRows) {
+		util.RespondWithError(writer, request, http.StatusNotFound, "Moderation rule not found", err)
+		return
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to delete moderation rule.", err)
+		return
+	}
+
+	err = queries.CreateModerationAction(request.Context(), database.CreateModerationActionParams{
+		ModeratorID: uuid.NullUUID{UUID: userID, Valid: true},
+		Action:      ModerationActionDeleteRule,
+		Note:        fmt.Sprintf("%s %s %s", dbRule.Kind, dbRule.Action, dbRule.Pattern),
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to record moderation action.", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to delete moderation rule.", err)
+		return
+	}
+
+	_, err = cfg.reloadModerationRules(request.Context())
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to reload moderation rules", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+	util.InfoLogger.Printf("Successfully deleted moderation rule: %s", ruleID)
+}
+
+func (cfg *ApiConfig) ModerationReloadHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling moderation rule reload.")
+
+	ruleCount, err := cfg.reloadModerationRules(request.Context())
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to reload moderation rules", err)
+		return
+	}
+
+	util.RespondWithJson(writer, request, http.StatusOK, map[string]int{"rules": ruleCount})
+	util.InfoLogger.Printf("Successfully reloaded %d moderation rules.", ruleCount)
+}
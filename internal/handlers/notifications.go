@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/auth"
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/notify"
+	"github.com/kwekkwekpatu/chirpy/internal/pagination"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+)
+
+type Notification struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Type      string     `json:"type"`
+	ActorID   *uuid.UUID `json:"actor_id"`
+	ChirpID   *uuid.UUID `json:"chirp_id"`
+	ReadAt    *time.Time `json:"read_at"`
+}
+
+type NotificationPage struct {
+	Notifications []Notification `json:"notifications"`
+	UnreadCount   int64          `json:"unread_count"`
+	NextCursor    string         `json:"next_cursor,omitempty"`
+}
+
+// notify delivers event through the configured notifier. Notifications are a
+// side effect of the request, so a failure is logged instead of returned.
+func (cfg *ApiConfig) notify(ctx context.Context, event notify.Event) {
+	err := cfg.notifier.Notify(ctx, event)
+	if err != nil {
+		util.ErrorLogger.Printf("Failed to deliver %s notification to %s: %s", event.Type, event.Recipient, err)
+	}
+}
+
+func notificationFromDatabase(dbNotification database.Notification) Notification {
+	notification := Notification{ID: dbNotification.ID, CreatedAt: dbNotification.CreatedAt, Type: dbNotification.Type}
+	if dbNotification.ActorID.Valid {
+		actorID := dbNotification.ActorID.UUID
+		notification.ActorID = &actorID
+	}
+	if dbNotification.ChirpID.Valid {
+		chirpID := dbNotification.ChirpID.UUID
+		notification.ChirpID = &chirpID
+	}
+	if dbNotification.ReadAt.Valid {
+		readAt := dbNotification.ReadAt.Time
+		notification.ReadAt = &readAt
+	}
+	return notification
+}
+
+func (cfg *ApiConfig) NotificationsReadHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling reading of notifications.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	pageParams, err := pagination.ParseParams(request.URL.Query())
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	cursorCreatedAt, cursorID := cursorArgs(pageParams.Cursor)
+	unreadOnly := request.URL.Query().Get("unread") == "true"
+
+	util.InfoLogger.Printf("Loading notifications for user: %s", userID)
+	dbNotifications, err := cfg.db.ReadNotifications(request.Context(), database.ReadNotificationsParams{
+		UserID:          userID,
+		UnreadOnly:      unreadOnly,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		PageLimit:       int32(pageParams.Limit + 1),
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read notifications", err)
+		return
+	}
+
+	unreadCount, err := cfg.db.CountUnreadNotifications(request.Context(), userID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to count notifications", err)
+		return
+	}
+
+	responseBody := NotificationPage{Notifications: []Notification{}, UnreadCount: unreadCount}
+	if len(dbNotifications) > pageParams.Limit {
+		dbNotifications = dbNotifications[:pageParams.Limit]
+		last := dbNotifications[len(dbNotifications)-1]
+		responseBody.NextCursor = pagination.EncodeCursor(pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	for _, dbNotification := range dbNotifications {
+		responseBody.Notifications = append(responseBody.Notifications, notificationFromDatabase(dbNotification))
+	}
+
+	util.RespondWithJson(writer, request, http.StatusOK, responseBody)
+	util.InfoLogger.Printf("Successfully read notifications.")
+}
+
+func (cfg *ApiConfig) NotificationsMarkReadHandler(writer http.ResponseWriter, request *http.Request) {
+	type parameters struct {
+		IDs []uuid.UUID `json:"ids"`
+	}
+
+	util.InfoLogger.Printf("Handling marking notifications as read.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	// An empty body or an empty list of ids marks every notification as read.
+	params := parameters{}
+	if request.ContentLength != 0 {
+		decoder := json.NewDecoder(request.Body)
+		err = decoder.Decode(&params)
+		if err != nil {
+			util.RespondWithError(writer, request, http.StatusBadRequest, "Error decoding parameters.", err)
+			return
+		}
+	}
+
+	markParams := database.MarkNotificationsReadParams{
+		UserID:  userID,
+		MarkAll: len(params.IDs) == 0,
+		Ids:     params.IDs,
+	}
+	if markParams.Ids == nil {
+		markParams.Ids = []uuid.UUID{}
+	}
+
+	util.InfoLogger.Printf("Marking notifications as read for user: %s", userID)
+	_, err = cfg.db.MarkNotificationsRead(request.Context(), markParams)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to mark notifications as read", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+	util.InfoLogger.Printf("Successfully marked notifications as read.")
+}
@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/pagination"
+)
+
+func cursorArgs(cursor *pagination.Cursor) (sql.NullTime, uuid.NullUUID) {
+	if cursor == nil {
+		return sql.NullTime{}, uuid.NullUUID{}
+	}
+	return sql.NullTime{Time: cursor.CreatedAt, Valid: true}, uuid.NullUUID{UUID: cursor.ID, Valid: true}
+}
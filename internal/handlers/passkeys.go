@@ -0,0 +1,475 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/auth"
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+	"github.com/kwekkwekpatu/chirpy/internal/webauthn"
+)
+
+const (
+	WebAuthnRegistration = "registration"
+	WebAuthnLogin        = "login"
+
+	DefaultPasskeyName   = "Passkey"
+	MaxPasskeyNameLength = 64
+)
+
+type Passkey struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	BackedUp   bool       `json:"backed_up"`
+	Transports []string   `json:"transports"`
+}
+
+func passkeyFromDatabase(dbPasskey database.Passkey) Passkey {
+	passkey := Passkey{
+		ID:         dbPasskey.ID,
+		Name:       dbPasskey.Name,
+		CreatedAt:  dbPasskey.CreatedAt,
+		BackedUp:   dbPasskey.BackedUp,
+		Transports: dbPasskey.Transports,
+	}
+	if passkey.Transports == nil {
+		passkey.Transports = []string{}
+	}
+	if dbPasskey.LastUsedAt.Valid {
+		lastUsedAt := dbPasskey.LastUsedAt.Time
+		passkey.LastUsedAt = &lastUsedAt
+	}
+	return passkey
+}
+
+// The user handle WebAuthn stores with a passkey is the user ID, which says
+// nothing about the user by itself.
+func userHandle(userID uuid.UUID) []byte {
+	return userID[:]
+}
+
+// startWebAuthnCeremony stores a new challenge for a registration or a login.
+func (cfg *ApiConfig) startWebAuthnCeremony(request *http.Request, ceremony string, userID uuid.NullUUID) (string, error) {
+	err := cfg.db.DeleteExpiredWebAuthnChallenges(request.Context(), time.Now().UTC())
+	if err != nil {
+		util.ErrorLogger.Printf("Failed to delete expired WebAuthn challenges: %s", err)
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", err
+	}
+	err = cfg.db.CreateWebAuthnChallenge(request.Context(), database.CreateWebAuthnChallengeParams{
+		Challenge: challenge,
+		Ceremony:  ceremony,
+		UserID:    userID,
+		ExpiresAt: time.Now().UTC().Add(webauthn.CeremonyTimeout),
+	})
+	if err != nil {
+		return "", err
+	}
+	return challenge, nil
+}
+
+// takeWebAuthnChallenge uses up the challenge of a ceremony, so a response
+// can only be verified once, whether it passes or not.
+func (cfg *ApiConfig) takeWebAuthnChallenge(request *http.Request, ceremony, challenge string) (database.WebauthnChallenge, error) {
+	dbChallenge, err := cfg.db.TakeWebAuthnChallenge(request.Context(), database.TakeWebAuthnChallengeParams{
+		Challenge: challenge,
+		Ceremony:  ceremony,
+	})
+	if err != nil {
+		return database.WebauthnChallenge{}, err
+	}
+	if !time.Now().UTC().Before(dbChallenge.ExpiresAt) {
+		return database.WebauthnChallenge{}, fmt.Errorf("WebAuthn challenge expired at %s", dbChallenge.ExpiresAt)
+	}
+	return dbChallenge, nil
+}
+
+type passkeyRegisterBeginParameters struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// confirmIdentity checks a fresh second factor before the account gains a
+// new way to log in: a TOTP or recovery code when two-factor authentication
+// is enabled, the password otherwise. Like verifySecondFactor, it must run
+// inside a transaction that is committed whether or not it succeeds.
+func confirmIdentity(ctx context.Context, queries *database.Queries, dbUser database.User, params passkeyRegisterBeginParameters) (bool, error) {
+	credential, err := queries.ReadTOTPCredentialForUpdate(ctx, dbUser.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+	if err == nil && credential.ConfirmedAt.Valid {
+		if params.Code == "" {
+			return false, nil
+		}
+		return verifySecondFactor(ctx, queries, dbUser.ID, params.Code)
+	}
+
+	if params.Password == "" {
+		return false, nil
+	}
+	return auth.CheckPasswordHash(params.Password, dbUser.HashedPassword) == nil, nil
+}
+
+// PasskeyRegisterBeginHandler starts a registration once the user has
+// confirmed their identity, so a stolen access token alone cannot add a
+// passkey. The challenge it hands out is what the finish step checks.
+func (cfg *ApiConfig) PasskeyRegisterBeginHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling start of passkey registration.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	decoder := json.NewDecoder(request.Body)
+	params := passkeyRegisterBeginParameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Error decoding parameters.", err)
+		return
+	}
+
+	dbUser, err := cfg.db.GetUserByID(request.Context(), userID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to start passkey registration.", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Confirming identity of user: %s", userID)
+	tx, err := cfg.dbConn.BeginTx(request.Context(), nil)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to start passkey registration.", err)
+		return
+	}
+	defer tx.Rollback()
+
+	ok, err := confirmIdentity(request.Context(), cfg.db.WithTx(tx), dbUser, params)
+	if err != nil || !ok {
+		rejectSecondFactor(writer, request, tx, err, "Invalid password or code", "Failed to start passkey registration.")
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to start passkey registration.", err)
+		return
+	}
+
+	dbPasskeys, err := cfg.db.ReadUserPasskeys(request.Context(), userID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to start passkey registration.", err)
+		return
+	}
+	existing := []webauthn.CredentialDescriptor{}
+	for _, dbPasskey := range dbPasskeys {
+		existing = append(existing, webauthn.CredentialDescriptor{
+			Type:       "public-key",
+			ID:         webauthn.EncodeID(dbPasskey.CredentialID),
+			Transports: dbPasskey.Transports,
+		})
+	}
+
+	challenge, err := cfg.startWebAuthnCeremony(request, WebAuthnRegistration, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to start passkey registration.", err)
+		return
+	}
+
+	displayName := dbUser.Email
+	if dbUser.Username.Valid {
+		displayName = dbUser.Username.String
+	}
+	options := cfg.webauthn.CreationOptions(challenge, webauthn.UserEntity{
+		ID:          webauthn.EncodeID(userHandle(userID)),
+		Name:        dbUser.Email,
+		DisplayName: displayName,
+	}, existing)
+
+	util.RespondWithJson(writer, request, http.StatusOK, options)
+	util.InfoLogger.Printf("Successfully started passkey registration for user: %s", userID)
+}
+
+func (cfg *ApiConfig) PasskeyRegisterFinishHandler(writer http.ResponseWriter, request *http.Request) {
+	type parameters struct {
+		Name       string                        `json:"name"`
+		Credential webauthn.RegistrationResponse `json:"credential"`
+	}
+
+	util.InfoLogger.Printf("Handling end of passkey registration.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	decoder := json.NewDecoder(request.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Error decoding parameters.", err)
+		return
+	}
+	if params.Name == "" {
+		params.Name = DefaultPasskeyName
+	}
+	if len(params.Name) > MaxPasskeyNameLength {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Passkey name is too long", nil)
+		return
+	}
+
+	challenge, err := params.Credential.Challenge()
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Passkey could not be verified", err)
+		return
+	}
+	dbChallenge, err := cfg.takeWebAuthnChallenge(request, WebAuthnRegistration, challenge)
+	if err == nil && dbChallenge.UserID.UUID != userID {
+		err = fmt.Errorf("WebAuthn challenge belongs to user %s", dbChallenge.UserID.UUID)
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Unknown or expired challenge", err)
+		return
+	}
+
+	credential, err := cfg.webauthn.VerifyRegistration(challenge, params.Credential)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Passkey could not be verified", err)
+		return
+	}
+	transports := credential.Transports
+	if transports == nil {
+		transports = []string{}
+	}
+
+	dbPasskey, err := cfg.db.CreatePasskey(request.Context(), database.CreatePasskeyParams{
+		UserID:         userID,
+		CredentialID:   credential.ID,
+		Name:           params.Name,
+		PublicKey:      credential.PublicKey,
+		Algorithm:      credential.Algorithm,
+		SignCount:      int64(credential.SignCount),
+		BackupEligible: credential.BackupEligible,
+		BackedUp:       credential.BackedUp,
+		Transports:     transports,
+	})
+	if isUniqueViolation(err) {
+		util.RespondWithError(writer, request, http.StatusConflict, "Passkey is already registered", err)
+		return
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to store passkey.", err)
+		return
+	}
+
+	util.RespondWithJson(writer, request, http.StatusCreated, passkeyFromDatabase(dbPasskey))
+	util.InfoLogger.Printf("Successfully registered passkey %s for user: %s", dbPasskey.ID, userID)
+}
+
+func (cfg *ApiConfig) PasskeysReadHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling reading of passkeys.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	dbPasskeys, err := cfg.db.ReadUserPasskeys(request.Context(), userID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read passkeys", err)
+		return
+	}
+
+	responseBody := []Passkey{}
+	for _, dbPasskey := range dbPasskeys {
+		responseBody = append(responseBody, passkeyFromDatabase(dbPasskey))
+	}
+	util.RespondWithJson(writer, request, http.StatusOK, responseBody)
+	util.InfoLogger.Printf("Successfully read passkeys.")
+}
+
+func (cfg *ApiConfig) PasskeyDeleteHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling deletion of passkey.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	passkeyID, err := uuid.Parse(request.PathValue("passkeyID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid passkey ID", err)
+		return
+	}
+
+	rows, err := cfg.db.DeleteUserPasskey(request.Context(), database.DeleteUserPasskeyParams{
+		ID:     passkeyID,
+		UserID: userID,
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to delete passkey", err)
+		return
+	}
+	if rows == 0 {
+		util.RespondWithError(writer, request, http.StatusNotFound, "Passkey not found", nil)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+	util.InfoLogger.Printf("Successfully deleted passkey %s of user: %s", passkeyID, userID)
+}
+
+// PasskeyLoginBeginHandler starts a login without an email: the passkey the
+// user picks tells who they are.
+func (cfg *ApiConfig) PasskeyLoginBeginHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling start of passkey login.")
+
+	challenge, err := cfg.startWebAuthnCeremony(request, WebAuthnLogin, uuid.NullUUID{})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to start passkey login.", err)
+		return
+	}
+
+	util.RespondWithJson(writer, request, http.StatusOK, cfg.webauthn.RequestOptions(challenge))
+}
+
+// PasskeyLoginFinishHandler logs in with a passkey assertion. Passkeys
+// require user verification, so they count as both factors and users with
+// TOTP are not asked for a code.
+func (cfg *ApiConfig) PasskeyLoginFinishHandler(writer http.ResponseWriter, request *http.Request) {
+	type parameters struct {
+		Credential webauthn.AssertionResponse `json:"credential"`
+	}
+
+	util.InfoLogger.Printf("Handling end of passkey login.")
+
+	decoder := json.NewDecoder(request.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Error decoding parameters.", err)
+		return
+	}
+
+	challenge, err := params.Credential.Challenge()
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Passkey could not be verified", err)
+		return
+	}
+	_, err = cfg.takeWebAuthnChallenge(request, WebAuthnLogin, challenge)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Unknown or expired challenge", err)
+		return
+	}
+
+	credentialID, err := params.Credential.CredentialID()
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Passkey could not be verified", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(request.Context(), nil)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, util.InternalServerError, err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.db.WithTx(tx)
+
+	// Locking the passkey keeps concurrent logins from racing on the
+	// signature counter.
+	dbPasskey, err := queries.ReadPasskeyByCredentialIDForUpdate(request.Context(), credentialID)
+	if errors.Is(err, sql.ErrNoRows) {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Passkey could not be verified", err)
+		return
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, util.InternalServerError, err)
+		return
+	}
+
+	assertion, err := cfg.webauthn.VerifyAssertion(challenge, webauthn.Credential{
+		ID:        dbPasskey.CredentialID,
+		PublicKey: dbPasskey.PublicKey,
+		Algorithm: dbPasskey.Algorithm,
+		SignCount: uint32(dbPasskey.SignCount),
+	}, userHandle(dbPasskey.UserID), params.Credential)
+	if errors.Is(err, webauthn.ErrSignCount) {
+		util.ErrorLogger.Printf("Signature counter of passkey %s went backwards, it may have been cloned", dbPasskey.ID)
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Passkey could not be verified", err)
+		return
+	}
+
+	err = queries.UsePasskey(request.Context(), database.UsePasskeyParams{
+		ID:        dbPasskey.ID,
+		SignCount: int64(assertion.SignCount),
+		BackedUp:  assertion.BackedUp,
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, util.InternalServerError, err)
+		return
+	}
+	dbUser, err := queries.GetUserByID(request.Context(), dbPasskey.UserID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, util.InternalServerError, err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, util.InternalServerError, err)
+		return
+	}
+
+	if dbUser.SuspendedAt.Valid {
+		util.RespondWithError(writer, request, http.StatusForbidden, "Account is suspended", fmt.Errorf("User %s was suspended at %s", dbUser.ID, dbUser.SuspendedAt.Time))
+		return
+	}
+
+	util.InfoLogger.Printf("Passkey %s verified for user: %s", dbPasskey.ID, dbUser.ID)
+	cfg.completeLogin(writer, request, dbUser)
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/kwekkwekpatu/chirpy/internal/auth"
+	"github.com/kwekkwekpatu/chirpy/internal/notify"
 	"github.com/kwekkwekpatu/chirpy/internal/util"
 	_ "github.com/lib/pq"
 )
@@ -69,6 +70,11 @@ func (cfg *ApiConfig) UpgradeUser(writer http.ResponseWriter, request *http.Requ
 	}
 	util.InfoLogger.Printf("Successfully upgraded user for id: %s", params.Data.UserID)
 
+	cfg.notify(request.Context(), notify.Event{
+		Type:      notify.TypeUpgraded,
+		Recipient: params.Data.UserID,
+	})
+
 	writer.WriteHeader(http.StatusNoContent)
 	util.InfoLogger.Printf("Upgrade finished.")
 	return
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+)
+
+// EmbeddedChirp is the original of a rechirp or quote-chirp. When the
+// original has been deleted only its id is kept and Tombstone is set.
+type EmbeddedChirp struct {
+	ID        uuid.UUID  `json:"id"`
+	Tombstone bool       `json:"tombstone"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	Body      string     `json:"body,omitempty"`
+	User_ID   *uuid.UUID `json:"user_id,omitempty"`
+}
+
+func isRechirp(dbChirp database.Chirp) bool {
+	return dbChirp.QuotedChirpID.Valid && dbChirp.Body == ""
+}
+
+// attachQuotedChirps embeds the originals of every rechirp and quote-chirp in
+// chirps with a single query.
+func (cfg *ApiConfig) attachQuotedChirps(ctx context.Context, chirps ChirpSlice) error {
+	quotedIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		if chirp.QuotedChirpID != nil {
+			quotedIDs = append(quotedIDs, *chirp.QuotedChirpID)
+		}
+	}
+	if len(quotedIDs) == 0 {
+		return nil
+	}
+
+	originals, err := cfg.db.ReadChirpsByIDs(ctx, quotedIDs)
+	if err != nil {
+		return err
+	}
+
+	originalsByID := make(map[uuid.UUID]database.Chirp, len(originals))
+	for _, original := range originals {
+		originalsByID[original.ID] = original
+	}
+
+	for i := range chirps {
+		if chirps[i].QuotedChirpID == nil {
+			continue
+		}
+		quotedID := *chirps[i].QuotedChirpID
+		original, ok := originalsByID[quotedID]
+		if !ok {
+			chirps[i].QuotedChirp = &EmbeddedChirp{ID: quotedID, Tombstone: true}
+			continue
+		}
+		authorID := original.UserID.UUID
+		chirps[i].QuotedChirp = &EmbeddedChirp{
+			ID:        original.ID,
+			CreatedAt: &original.CreatedAt,
+			UpdatedAt: &original.UpdatedAt,
+			Body:      original.Body,
+			User_ID:   &authorID,
+		}
+	}
+	return nil
+}
@@ -1,17 +1,50 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/kwekkwekpatu/chirpy/internal/auth"
+	"github.com/kwekkwekpatu/chirpy/internal/database"
 	"github.com/kwekkwekpatu/chirpy/internal/util"
 )
 
 type RefreshResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
+// issueRefreshToken creates and stores a new refresh token in the family,
+// recording the device of the request that asked for it.
+func issueRefreshToken(request *http.Request, queries *database.Queries, userID uuid.UUID, familyID uuid.UUID) (string, error) {
+	refreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	refreshDuration := time.Duration(RefreshExpirationDuration) * time.Second
+	_, err = queries.CreateRefreshToken(request.Context(), database.CreateRefreshTokenParams{
+		Token:     refreshToken,
+		ExpiresAt: time.Now().UTC().Add(refreshDuration),
+		UserID:    userID,
+		FamilyID:  familyID,
+		UserAgent: request.UserAgent(),
+		Ip:        clientIP(request),
+	})
+	if err != nil {
+		return "", err
+	}
+	return refreshToken, nil
+}
+
+// RefreshHandler trades a refresh token for a new access token and a new
+// refresh token. The old refresh token is revoked, and presenting a revoked
+// token again revokes its whole family: either the client or an attacker
+// holds a copy that should no longer exist.
 func (cfg *ApiConfig) RefreshHandler(writer http.ResponseWriter, request *http.Request) {
 	util.InfoLogger.Printf("Handling refresh.")
 	util.InfoLogger.Printf("Extracting and validating Refresh token")
@@ -22,26 +55,83 @@ func (cfg *ApiConfig) RefreshHandler(writer http.ResponseWriter, request *http.R
 		return
 	}
 
-	dbToken, err := cfg.db.ReadRefreshToken(request.Context(), tokenString)
-	if err != nil || (dbToken.RevokedAt.Valid && time.Now().After(dbToken.RevokedAt.Time)) {
+	tx, err := cfg.dbConn.BeginTx(request.Context(), nil)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to refresh token.", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.db.WithTx(tx)
+
+	// Locking the row makes concurrent refreshes with the same token wait,
+	// so only one of them can rotate it.
+	dbToken, err := queries.ReadRefreshTokenForUpdate(request.Context(), tokenString)
+	if errors.Is(err, sql.ErrNoRows) {
 		util.RespondWithError(writer, request, http.StatusUnauthorized, "No valid token in database.", err)
 		return
 	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to refresh token.", err)
+		return
+	}
+
+	if dbToken.RevokedAt.Valid {
+		util.ErrorLogger.Printf("Revoked refresh token reused, revoking family %s of user %s", dbToken.FamilyID, dbToken.UserID)
+		err = queries.RevokeRefreshTokenFamily(request.Context(), dbToken.FamilyID)
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to revoke token family.", err)
+			return
+		}
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "No valid token in database.", fmt.Errorf("Refresh token was already revoked at %s", dbToken.RevokedAt.Time))
+		return
+	}
+
+	if !time.Now().UTC().Before(dbToken.ExpiresAt) {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Refresh token has expired.", fmt.Errorf("Refresh token expired at %s", dbToken.ExpiresAt))
+		return
+	}
+
+	// A suspended user keeps their refresh tokens, so check on every rotation.
+	dbUser, err := queries.GetUserByID(request.Context(), dbToken.UserID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to refresh token.", err)
+		return
+	}
+	if dbUser.SuspendedAt.Valid {
+		util.RespondWithError(writer, request, http.StatusForbidden, "Account is suspended", fmt.Errorf("User %s was suspended at %s", dbUser.ID, dbUser.SuspendedAt.Time))
+		return
+	}
+
+	util.InfoLogger.Printf("Rotating refresh token.")
+	err = queries.RevokeRefreshToken(request.Context(), tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to revoke old refresh token.", err)
+		return
+	}
+
+	newRefreshToken, err := issueRefreshToken(request, queries, dbToken.UserID, dbToken.FamilyID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to store refresh_token.", err)
+		return
+	}
 
-	dbUser, err := cfg.db.GetUserFromRefreshToken(request.Context(), tokenString)
+	err = tx.Commit()
 	if err != nil {
-		util.RespondWithError(writer, request, http.StatusUnauthorized, "Refresh token does not belong to any known users.", err)
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to refresh token.", err)
 		return
 	}
 
 	util.InfoLogger.Printf("Generating new token.")
-	newToken, err := auth.MakeJWT(dbUser.ID, cfg.jwtSecret, time.Hour)
+	newToken, err := cfg.keyring.MakeSessionJWT(dbToken.UserID, dbToken.FamilyID, time.Hour)
 	if err != nil {
 		util.RespondWithError(writer, request, http.StatusUnauthorized, "Failed to generate new token.", err)
 		return
 	}
 
-	responseBody := RefreshResponse{Token: newToken}
+	responseBody := RefreshResponse{Token: newToken, RefreshToken: newRefreshToken}
 	util.RespondWithJson(writer, request, http.StatusOK, responseBody)
 	util.InfoLogger.Printf("Successfully refreshed token.")
 
@@ -0,0 +1,472 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/auth"
+	"github.com/kwekkwekpatu/chirpy/internal/authz"
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/pagination"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+)
+
+const MaxReportDetailsLength = 1000
+
+// ReportReasonAutomated marks reports raised by a moderation flag rule. Users
+// cannot pick it.
+const ReportReasonAutomated = "automated"
+
+var reportReasons = map[string]bool{
+	"spam":           true,
+	"harassment":     true,
+	"hate":           true,
+	"violence":       true,
+	"sexual_content": true,
+	"misinformation": true,
+	"impersonation":  true,
+	"other":          true,
+}
+
+// Moderation actions as they appear in the audit trail. The first three are
+// also the ways to resolve a report.
+const (
+	ModerationActionDismiss     = "dismiss"
+	ModerationActionHideChirp   = "hide_chirp"
+	ModerationActionSuspendUser = "suspend_user"
+	ModerationActionRestore     = "restore_chirp"
+	ModerationActionAddRule     = "create_moderation_rule"
+	ModerationActionDeleteRule  = "delete_moderation_rule"
+	ModerationActionGrantRole   = "grant_role"
+	ModerationActionRevokeRole  = "revoke_role"
+)
+
+const (
+	ReportStatusOpen     = "open"
+	ReportStatusResolved = "resolved"
+)
+
+type Report struct {
+	ID             uuid.UUID  `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ReporterID     *uuid.UUID `json:"reporter_id"`
+	ChirpID        *uuid.UUID `json:"chirp_id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	Reason         string     `json:"reason"`
+	Details        string     `json:"details"`
+	Status         string     `json:"status"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy     *uuid.UUID `json:"resolved_by,omitempty"`
+	Resolution     string     `json:"resolution,omitempty"`
+	ResolutionNote string     `json:"resolution_note,omitempty"`
+}
+
+type ReportPage struct {
+	Reports    []Report `json:"reports"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+type ModerationAction struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ModeratorID *uuid.UUID `json:"moderator_id"`
+	Action      string     `json:"action"`
+	ReportID    *uuid.UUID `json:"report_id,omitempty"`
+	ChirpID     *uuid.UUID `json:"chirp_id,omitempty"`
+	UserID      *uuid.UUID `json:"user_id,omitempty"`
+	Note        string     `json:"note"`
+}
+
+type ModerationActionPage struct {
+	Actions    []ModerationAction `json:"actions"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+func reportFromDatabase(dbReport database.Report) Report {
+	report := Report{
+		ID:             dbReport.ID,
+		CreatedAt:      dbReport.CreatedAt,
+		ReporterID:     uuidPointer(dbReport.ReporterID),
+		ChirpID:        uuidPointer(dbReport.ChirpID),
+		UserID:         dbReport.UserID,
+		Reason:         dbReport.Reason,
+		Details:        dbReport.Details,
+		Status:         ReportStatusOpen,
+		ResolvedBy:     uuidPointer(dbReport.ResolvedBy),
+		Resolution:     dbReport.Resolution.String,
+		ResolutionNote: dbReport.ResolutionNote.String,
+	}
+	if dbReport.ResolvedAt.Valid {
+		resolvedAt := dbReport.ResolvedAt.Time
+		report.ResolvedAt = &resolvedAt
+		report.Status = ReportStatusResolved
+	}
+	return report
+}
+
+func moderationActionFromDatabase(dbAction database.ModerationAction) ModerationAction {
+	return ModerationAction{
+		ID:          dbAction.ID,
+		CreatedAt:   dbAction.CreatedAt,
+		ModeratorID: uuidPointer(dbAction.ModeratorID),
+		Action:      dbAction.Action,
+		ReportID:    uuidPointer(dbAction.ReportID),
+		ChirpID:     uuidPointer(dbAction.ChirpID),
+		UserID:      uuidPointer(dbAction.UserID),
+		Note:        dbAction.Note,
+	}
+}
+
+type reportParameters struct {
+	Reason  string `json:"reason"`
+	Details string `json:"details"`
+}
+
+func decodeReportParameters(request *http.Request) (reportParameters, error) {
+	util.InfoLogger.Printf("Loading request parameter.")
+	decoder := json.NewDecoder(request.Body)
+	params := reportParameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		return reportParameters{}, fmt.Errorf("Error decoding parameters.")
+	}
+	if !reportReasons[params.Reason] {
+		return reportParameters{}, fmt.Errorf("Unknown report reason %q", params.Reason)
+	}
+	if len(params.Details) > MaxReportDetailsLength {
+		return reportParameters{}, fmt.Errorf("Report details are too long")
+	}
+	return params, nil
+}
+
+func (cfg *ApiConfig) ChirpReportHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling chirp report.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Reading request ChirpID.")
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid chirpID", err)
+		return
+	}
+
+	params, err := decodeReportParameters(request)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	dbChirp, err := cfg.db.ReadChirp(request.Context(), chirpID)
+	if err != nil || !dbChirp.UserID.Valid {
+		util.RespondWithError(writer, request, http.StatusNotFound, "chirp not found", err)
+		return
+	}
+	if dbChirp.UserID.UUID == userID {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Users cannot report their own chirps", fmt.Errorf("User %s tried to report their own chirp", userID))
+		return
+	}
+
+	cfg.createReport(writer, request, database.CreateReportParams{
+		ReporterID: uuid.NullUUID{UUID: userID, Valid: true},
+		ChirpID:    uuid.NullUUID{UUID: chirpID, Valid: true},
+		UserID:     dbChirp.UserID.UUID,
+		Reason:     params.Reason,
+		Details:    params.Details,
+	})
+}
+
+func (cfg *ApiConfig) UserReportHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling user report.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Reading request UserID.")
+	reportedID, err := uuid.Parse(request.PathValue("userID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid userID", err)
+		return
+	}
+
+	params, err := decodeReportParameters(request)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	if reportedID == userID {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Users cannot report themselves", fmt.Errorf("User %s tried to report themselves", userID))
+		return
+	}
+
+	util.InfoLogger.Printf("Checking if user exists")
+	_, err = cfg.db.GetUserByID(request.Context(), reportedID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusNotFound, "Failed to find user", err)
+		return
+	}
+
+	cfg.createReport(writer, request, database.CreateReportParams{
+		ReporterID: uuid.NullUUID{UUID: userID, Valid: true},
+		UserID:     reportedID,
+		Reason:     params.Reason,
+		Details:    params.Details,
+	})
+}
+
+func (cfg *ApiConfig) createReport(writer http.ResponseWriter, request *http.Request, reportParams database.CreateReportParams) {
+	util.InfoLogger.Printf("Attempting to create report on user %s", reportParams.UserID)
+	dbReport, err := cfg.db.CreateReport(request.Context(), reportParams)
+	if isUniqueViolation(err) {
+		util.RespondWithError(writer, request, http.StatusConflict, "Already reported", err)
+		return
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to create report.", err)
+		return
+	}
+
+	util.RespondWithJson(writer, request, http.StatusCreated, reportFromDatabase(dbReport))
+	util.InfoLogger.Printf("Successfully created report: %s", dbReport.ID)
+}
+
+// ReportsReadHandler serves the review queue: open reports oldest first, or
+// with status=resolved the outcomes, most recently resolved first.
+func (cfg *ApiConfig) ReportsReadHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling reading of reports.")
+
+	pageParams, err := pagination.ParseParams(request.URL.Query())
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	cursorTime, cursorID := cursorArgs(pageParams.Cursor)
+
+	status := request.URL.Query().Get("status")
+	var dbReports []database.Report
+	switch status {
+	case "", ReportStatusOpen:
+		dbReports, err = cfg.db.ReadOpenReports(request.Context(), database.ReadOpenReportsParams{
+			CursorCreatedAt: cursorTime,
+			CursorID:        cursorID,
+			PageLimit:       int32(pageParams.Limit + 1),
+		})
+	case ReportStatusResolved:
+		dbReports, err = cfg.db.ReadResolvedReports(request.Context(), database.ReadResolvedReportsParams{
+			CursorResolvedAt: cursorTime,
+			CursorID:         cursorID,
+			PageLimit:        int32(pageParams.Limit + 1),
+		})
+	default:
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid status", fmt.Errorf("Unknown report status %q", status))
+		return
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read reports", err)
+		return
+	}
+
+	responseBody := ReportPage{Reports: []Report{}}
+	if len(dbReports) > pageParams.Limit {
+		dbReports = dbReports[:pageParams.Limit]
+		last := dbReports[len(dbReports)-1]
+		cursor := pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		if last.ResolvedAt.Valid {
+			cursor.CreatedAt = last.ResolvedAt.Time
+		}
+		responseBody.NextCursor = pagination.EncodeCursor(cursor)
+	}
+	for _, dbReport := range dbReports {
+		responseBody.Reports = append(responseBody.Reports, reportFromDatabase(dbReport))
+	}
+
+	util.RespondWithJson(writer, request, http.StatusOK, responseBody)
+	util.InfoLogger.Printf("Successfully read reports.")
+}
+
+func (cfg *ApiConfig) ReportResolveHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling report resolution.")
+
+	principal, _ := authz.FromContext(request.Context())
+	moderatorID := principal.UserID
+
+	util.InfoLogger.Printf("Reading request ReportID.")
+	reportID, err := uuid.Parse(request.PathValue("reportID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid reportID", err)
+		return
+	}
+
+	type parameters struct {
+		Action string `json:"action"`
+		Note   string `json:"note"`
+	}
+
+	util.InfoLogger.Printf("Loading request parameter.")
+	decoder := json.NewDecoder(request.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Error decoding parameters.", err)
+		return
+	}
+	var actionPermission authz.Permission
+	switch params.Action {
+	case ModerationActionDismiss:
+		actionPermission = authz.ResolveReports
+	case ModerationActionHideChirp:
+		actionPermission = authz.HideChirps
+	case ModerationActionSuspendUser:
+		actionPermission = authz.SuspendUsers
+	default:
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid action", fmt.Errorf("Unknown report action %q", params.Action))
+		return
+	}
+	if !principal.Permissions.Has(actionPermission) {
+		util.RespondWithError(writer, request, http.StatusForbidden, "Missing permission "+string(actionPermission), fmt.Errorf("User %s lacks %s", moderatorID, actionPermission))
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(request.Context(), nil)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to resolve report.", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.db.WithTx(tx)
+
+	dbReport, err := queries.ReadReportForUpdate(request.Context(), reportID)
+	if errors.Is(err, sql.ErrNoRows) {
+		util.RespondWithError(writer, request, http.StatusNotFound, "Report not found", err)
+		return
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read report.", err)
+		return
+	}
+	if dbReport.ResolvedAt.Valid {
+		util.RespondWithError(writer, request, http.StatusConflict, "Report is already resolved", fmt.Errorf("Report %s was resolved at %s", reportID, dbReport.ResolvedAt.Time))
+		return
+	}
+
+	util.InfoLogger.Printf("Moderator %s resolves report %s with %s", moderatorID, reportID, params.Action)
+	switch params.Action {
+	case ModerationActionHideChirp:
+		if !dbReport.ChirpID.Valid {
+			util.RespondWithError(writer, request, http.StatusBadRequest, "Report is not about a chirp", fmt.Errorf("Report %s has no chirp", reportID))
+			return
+		}
+		_, err = queries.HideChirp(request.Context(), database.HideChirpParams{
+			ID:       dbReport.ChirpID.UUID,
+			HiddenAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		})
+	case ModerationActionSuspendUser:
+		_, err = queries.SuspendUser(request.Context(), dbReport.UserID)
+		if err == nil {
+			// Access tokens cannot be revoked, they run out within the hour.
+			err = queries.RevokeUserRefreshTokens(request.Context(), dbReport.UserID)
+		}
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to resolve report.", err)
+		return
+	}
+
+	resolvedReport, err := queries.ResolveReport(request.Context(), database.ResolveReportParams{
+		ID:             reportID,
+		ResolvedBy:     uuid.NullUUID{UUID: moderatorID, Valid: true},
+		Resolution:     sql.NullString{String: params.Action, Valid: true},
+		ResolutionNote: sql.NullString{String: params.Note, Valid: params.Note != ""},
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to resolve report.", err)
+		return
+	}
+
+	err = queries.CreateModerationAction(request.Context(), database.CreateModerationActionParams{
+		ModeratorID: uuid.NullUUID{UUID: moderatorID, Valid: true},
+		Action:      params.Action,
+		ReportID:    uuid.NullUUID{UUID: reportID, Valid: true},
+		ChirpID:     dbReport.ChirpID,
+		UserID:      uuid.NullUUID{UUID: dbReport.UserID, Valid: true},
+		Note:        params.Note,
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to record moderation action.", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to resolve report.", err)
+		return
+	}
+
+	if params.Action == ModerationActionHideChirp {
+		cfg.publishChirpEvent(ChirpDeletedEvent, dbReport.UserID, map[string]uuid.UUID{"id": dbReport.ChirpID.UUID})
+	}
+
+	util.RespondWithJson(writer, request, http.StatusOK, reportFromDatabase(resolvedReport))
+	util.InfoLogger.Printf("Successfully resolved report: %s", reportID)
+}
+
+func (cfg *ApiConfig) ModerationActionsReadHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling reading of moderation actions.")
+
+	pageParams, err := pagination.ParseParams(request.URL.Query())
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	cursorCreatedAt, cursorID := cursorArgs(pageParams.Cursor)
+
+	dbActions, err := cfg.db.ReadModerationActions(request.Context(), database.ReadModerationActionsParams{
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		PageLimit:       int32(pageParams.Limit + 1),
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read moderation actions", err)
+		return
+	}
+
+	responseBody := ModerationActionPage{Actions: []ModerationAction{}}
+	if len(dbActions) > pageParams.Limit {
+		dbActions = dbActions[:pageParams.Limit]
+		last := dbActions[len(dbActions)-1]
+		responseBody.NextCursor = pagination.EncodeCursor(pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	for _, dbAction := range dbActions {
+		responseBody.Actions = append(responseBody.Actions, moderationActionFromDatabase(dbAction))
+	}
+
+	util.RespondWithJson(writer, request, http.StatusOK, responseBody)
+	util.InfoLogger.Printf("Successfully read moderation actions.")
+}
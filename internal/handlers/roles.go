@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/authz"
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+)
+
+type Role struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
+
+type UserRoles struct {
+	UserID      uuid.UUID          `json:"user_id"`
+	Roles       []string           `json:"roles"`
+	Permissions []authz.Permission `json:"permissions"`
+}
+
+// bootstrapAdmin grants the admin role to the user with the given email, so
+// a fresh deployment has someone who can hand out roles. It does nothing if
+// that user does not exist yet.
+func (cfg *ApiConfig) bootstrapAdmin(ctx context.Context, email string) error {
+	granted, err := cfg.db.GrantRoleByEmail(ctx, database.GrantRoleByEmailParams{Email: email, RoleName: "admin"})
+	if err != nil {
+		return err
+	}
+	if granted > 0 {
+		util.InfoLogger.Printf("Granted the admin role to %s", email)
+	}
+	return nil
+}
+
+func (cfg *ApiConfig) RolesReadHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling reading of roles.")
+
+	dbRoles, err := cfg.db.ReadRoles(request.Context())
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read roles", err)
+		return
+	}
+	dbPermissions, err := cfg.db.ReadRolePermissions(request.Context())
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read roles", err)
+		return
+	}
+
+	permissionsByRole := map[string][]string{}
+	for _, dbPermission := range dbPermissions {
+		permissionsByRole[dbPermission.Name] = append(permissionsByRole[dbPermission.Name], dbPermission.Permission)
+	}
+
+	responseBody := []Role{}
+	for _, dbRole := range dbRoles {
+		permissions := permissionsByRole[dbRole.Name]
+		if permissions == nil {
+			permissions = []string{}
+		}
+		responseBody = append(responseBody, Role{Name: dbRole.Name, Permissions: permissions})
+	}
+
+	util.RespondWithJson(writer, request, http.StatusOK, responseBody)
+	util.InfoLogger.Printf("Successfully read roles.")
+}
+
+func (cfg *ApiConfig) UserRolesReadHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling reading of user roles.")
+
+	util.InfoLogger.Printf("Reading request UserID.")
+	userID, err := uuid.Parse(request.PathValue("userID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid userID", err)
+		return
+	}
+
+	cfg.respondWithUserRoles(writer, request, userID)
+}
+
+func (cfg *ApiConfig) respondWithUserRoles(writer http.ResponseWriter, request *http.Request, userID uuid.UUID) {
+	roles, err := cfg.db.ReadUserRoles(request.Context(), userID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read user roles", err)
+		return
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+	permissions, err := cfg.userPermissions(request.Context(), uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read permissions", err)
+		return
+	}
+
+	util.RespondWithJson(writer, request, http.StatusOK, UserRoles{UserID: userID, Roles: roles, Permissions: permissions.List()})
+	util.InfoLogger.Printf("Successfully read roles of user: %s", userID)
+}
+
+func (cfg *ApiConfig) RoleGrantHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling role grant.")
+	cfg.changeUserRole(writer, request, ModerationActionGrantRole)
+}
+
+func (cfg *ApiConfig) RoleRevokeHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling role revocation.")
+	cfg.changeUserRole(writer, request, ModerationActionRevokeRole)
+}
+
+// changeUserRole grants or revokes the role in the path and records the
+// change in the audit trail.
+func (cfg *ApiConfig) changeUserRole(writer http.ResponseWriter, request *http.Request, action string) {
+	principal, _ := authz.FromContext(request.Context())
+
+	util.InfoLogger.Printf("Reading request UserID.")
+	userID, err := uuid.Parse(request.PathValue("userID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid userID", err)
+		return
+	}
+
+	dbRole, err := cfg.db.ReadRole(request.Context(), request.PathValue("role"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusNotFound, "Role not found", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Checking if user exists")
+	_, err = cfg.db.GetUserByID(request.Context(), userID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusNotFound, "Failed to find user", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(request.Context(), nil)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to change roles.", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.db.WithTx(tx)
+
+	util.InfoLogger.Printf("User %s: %s %s for user %s", principal.UserID, action, dbRole.Name, userID)
+	roleParams := database.GrantRoleParams{UserID: userID, RoleID: dbRole.ID}
+	if action == ModerationActionGrantRole {
+		err = queries.GrantRole(request.Context(), roleParams)
+	} else {
+		var revoked int64
+		revoked, err = queries.RevokeRole(request.Context(), database.RevokeRoleParams(roleParams))
+		if err == nil && revoked == 0 {
+			err = sql.ErrNoRows
+		}
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		util.RespondWithError(writer, request, http.StatusNotFound, "User does not have this role", fmt.Errorf("User %s does not have role %s", userID, dbRole.Name))
+		return
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to change roles.", err)
+		return
+	}
+
+	err = queries.CreateModerationAction(request.Context(), database.CreateModerationActionParams{
+		ModeratorID: uuid.NullUUID{UUID: principal.UserID, Valid: true},
+		Action:      action,
+		UserID:      uuid.NullUUID{UUID: userID, Valid: true},
+		Note:        dbRole.Name,
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to record moderation action.", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to change roles.", err)
+		return
+	}
+
+	cfg.respondWithUserRoles(writer, request, userID)
+}
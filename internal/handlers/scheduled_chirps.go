@@ -0,0 +1,388 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/auth"
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/pagination"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+)
+
+const ScheduledChirpPollInterval = 5 * time.Second
+
+// ScheduledChirp is a chirp that has not been published yet. It lives in its
+// own table, so it cannot show up in any chirp listing before it is due.
+// When it is published the chirp keeps the same id.
+type ScheduledChirp struct {
+	ID            uuid.UUID   `json:"id"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+	PublishAt     time.Time   `json:"publish_at"`
+	Body          string      `json:"body"`
+	User_ID       uuid.UUID   `json:"user_id"`
+	ParentID      *uuid.UUID  `json:"parent_id"`
+	QuotedChirpID *uuid.UUID  `json:"quoted_chirp_id"`
+	MediaIDs      []uuid.UUID `json:"media_ids"`
+	FailedAt      *time.Time  `json:"failed_at"`
+	Failure       string      `json:"failure,omitempty"`
+}
+
+type ScheduledChirpPage struct {
+	ScheduledChirps []ScheduledChirp `json:"scheduled_chirps"`
+	NextCursor      string           `json:"next_cursor,omitempty"`
+}
+
+func scheduledChirpFromDatabase(dbScheduled database.ScheduledChirp) ScheduledChirp {
+	scheduled := ScheduledChirp{
+		ID:        dbScheduled.ID,
+		CreatedAt: dbScheduled.CreatedAt,
+		UpdatedAt: dbScheduled.UpdatedAt,
+		PublishAt: dbScheduled.PublishAt,
+		Body:      dbScheduled.Body,
+		User_ID:   dbScheduled.UserID,
+		MediaIDs:  dbScheduled.MediaIds,
+		Failure:   dbScheduled.Failure.String,
+	}
+	if scheduled.MediaIDs == nil {
+		scheduled.MediaIDs = []uuid.UUID{}
+	}
+	if dbScheduled.ParentID.Valid {
+		parentID := dbScheduled.ParentID.UUID
+		scheduled.ParentID = &parentID
+	}
+	if dbScheduled.QuotedChirpID.Valid {
+		quotedChirpID := dbScheduled.QuotedChirpID.UUID
+		scheduled.QuotedChirpID = &quotedChirpID
+	}
+	if dbScheduled.FailedAt.Valid {
+		failedAt := dbScheduled.FailedAt.Time
+		scheduled.FailedAt = &failedAt
+	}
+	return scheduled
+}
+
+// scheduleChirp finishes a ChirpHandler request that has a publish_at. The
+// chirp has already been validated like an immediate one.
+func (cfg *ApiConfig) scheduleChirp(writer http.ResponseWriter, request *http.Request, chirpParams database.CreateChirpParams, mediaIDs []uuid.UUID, publishAt time.Time) {
+	if !publishAt.After(time.Now()) {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "publish_at must be in the future", fmt.Errorf("publish_at %s is in the past", publishAt))
+		return
+	}
+
+	util.InfoLogger.Printf("Checking media of scheduled chirp.")
+	for _, mediaID := range mediaIDs {
+		dbMedia, err := cfg.db.ReadMedia(request.Context(), mediaID)
+		if err != nil || dbMedia.UserID != chirpParams.UserID.UUID || dbMedia.ChirpID.Valid {
+			util.RespondWithError(writer, request, http.StatusBadRequest, errMediaNotFound.Error(), fmt.Errorf("Media %s cannot be attached: %v", mediaID, err))
+			return
+		}
+	}
+	if mediaIDs == nil {
+		mediaIDs = []uuid.UUID{}
+	}
+
+	util.InfoLogger.Printf("Attempting to schedule chirp for user_id: %s", chirpParams.UserID.UUID)
+	dbScheduled, err := cfg.db.CreateScheduledChirp(request.Context(), database.CreateScheduledChirpParams{
+		UserID:        chirpParams.UserID.UUID,
+		Body:          chirpParams.Body,
+		ParentID:      chirpParams.ParentID,
+		QuotedChirpID: chirpParams.QuotedChirpID,
+		MediaIds:      mediaIDs,
+		PublishAt:     publishAt.UTC(),
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to schedule chirp.", err)
+		return
+	}
+
+	util.RespondWithJson(writer, request, http.StatusCreated, scheduledChirpFromDatabase(dbScheduled))
+	util.InfoLogger.Printf("Successfully scheduled chirp %s for %s", dbScheduled.ID, dbScheduled.PublishAt)
+}
+
+func (cfg *ApiConfig) ScheduledChirpsReadHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling reading of scheduled chirps.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	pageParams, err := pagination.ParseParams(request.URL.Query())
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	// Pending chirps are listed soonest first, so the cursor holds publish_at.
+	cursorPublishAt, cursorID := cursorArgs(pageParams.Cursor)
+
+	util.InfoLogger.Printf("Loading scheduled chirps of user: %s", userID)
+	dbScheduled, err := cfg.db.ReadScheduledChirps(request.Context(), database.ReadScheduledChirpsParams{
+		UserID:          userID,
+		CursorPublishAt: cursorPublishAt,
+		CursorID:        cursorID,
+		PageLimit:       int32(pageParams.Limit + 1),
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read scheduled chirps", err)
+		return
+	}
+
+	responseBody := ScheduledChirpPage{ScheduledChirps: []ScheduledChirp{}}
+	if len(dbScheduled) > pageParams.Limit {
+		dbScheduled = dbScheduled[:pageParams.Limit]
+		last := dbScheduled[len(dbScheduled)-1]
+		responseBody.NextCursor = pagination.EncodeCursor(pagination.Cursor{CreatedAt: last.PublishAt, ID: last.ID})
+	}
+	for _, scheduled := range dbScheduled {
+		responseBody.ScheduledChirps = append(responseBody.ScheduledChirps, scheduledChirpFromDatabase(scheduled))
+	}
+
+	util.RespondWithJson(writer, request, http.StatusOK, responseBody)
+	util.InfoLogger.Printf("Successfully read scheduled chirps.")
+}
+
+func (cfg *ApiConfig) ScheduledChirpRescheduleHandler(writer http.ResponseWriter, request *http.Request) {
+	type parameters struct {
+		PublishAt *time.Time `json:"publish_at"`
+	}
+
+	util.InfoLogger.Printf("Handling rescheduling of chirp.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Reading request ScheduledChirpID.")
+	scheduledID, err := uuid.Parse(request.PathValue("scheduledChirpID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid scheduledChirpID", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Loading request parameter.")
+	decoder := json.NewDecoder(request.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Error decoding parameters.", err)
+		return
+	}
+	if params.PublishAt == nil || !params.PublishAt.After(time.Now()) {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "publish_at must be in the future", fmt.Errorf("Invalid publish_at"))
+		return
+	}
+
+	// Rescheduling also clears a failure, which is how a failed chirp is
+	// retried.
+	util.InfoLogger.Printf("Attempting to reschedule chirp %s to %s", scheduledID, *params.PublishAt)
+	dbScheduled, err := cfg.db.RescheduleChirp(request.Context(), database.RescheduleChirpParams{
+		ID:        scheduledID,
+		UserID:    userID,
+		PublishAt: params.PublishAt.UTC(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		util.RespondWithError(writer, request, http.StatusNotFound, "Scheduled chirp not found", err)
+		return
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to reschedule chirp.", err)
+		return
+	}
+
+	util.RespondWithJson(writer, request, http.StatusOK, scheduledChirpFromDatabase(dbScheduled))
+	util.InfoLogger.Printf("Successfully rescheduled chirp: %s", scheduledID)
+}
+
+func (cfg *ApiConfig) ScheduledChirpCancelHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling cancellation of scheduled chirp.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Reading request ScheduledChirpID.")
+	scheduledID, err := uuid.Parse(request.PathValue("scheduledChirpID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid scheduledChirpID", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Attempting to cancel scheduled chirp: %s", scheduledID)
+	deleted, err := cfg.db.DeleteScheduledChirp(request.Context(), database.DeleteScheduledChirpParams{ID: scheduledID, UserID: userID})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to cancel scheduled chirp.", err)
+		return
+	}
+	// Also reached when the publisher got there first.
+	if deleted == 0 {
+		util.RespondWithError(writer, request, http.StatusNotFound, "Scheduled chirp not found", fmt.Errorf("No scheduled chirp %s for user %s", scheduledID, userID))
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+	util.InfoLogger.Printf("Successfully cancelled scheduled chirp: %s", scheduledID)
+}
+
+// RunScheduledChirpPublisher publishes due scheduled chirps until ctx is
+// done. Every server replica runs one: due rows are claimed with FOR UPDATE
+// SKIP LOCKED, so each chirp is published by exactly one of them.
+func (cfg *ApiConfig) RunScheduledChirpPublisher(ctx context.Context) {
+	util.InfoLogger.Printf("Starting scheduled chirp publisher.")
+	ticker := time.NewTicker(ScheduledChirpPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			published, err := cfg.publishNextScheduledChirp(ctx)
+			if err != nil {
+				util.ErrorLogger.Printf("Failed to publish scheduled chirps: %s", err)
+				break
+			}
+			if !published {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			util.InfoLogger.Printf("Stopping scheduled chirp publisher.")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishNextScheduledChirp publishes the oldest due chirp that no other
+// replica is working on. It reports whether there was one.
+func (cfg *ApiConfig) publishNextScheduledChirp(ctx context.Context) (bool, error) {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	queries := cfg.db.WithTx(tx)
+
+	// publish_at is stored as UTC in a column without a time zone, so the
+	// current time is passed in rather than compared against NOW().
+	scheduled, err := queries.ClaimDueScheduledChirp(ctx, time.Now().UTC())
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// A chirp that can never be published, e.g. because its parent was
+	// deleted, is marked as failed instead of being retried forever. The
+	// savepoint keeps the row locked while its partial work is undone.
+	_, err = tx.ExecContext(ctx, "SAVEPOINT publish_scheduled_chirp")
+	if err != nil {
+		return false, err
+	}
+
+	util.InfoLogger.Printf("Publishing scheduled chirp: %s", scheduled.ID)
+	chirp, mentionedIDs, publishErr := createScheduledChirp(ctx, queries, scheduled)
+	if publishErr != nil {
+		util.ErrorLogger.Printf("Failed to publish scheduled chirp %s: %s", scheduled.ID, publishErr)
+		_, err = tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT publish_scheduled_chirp")
+		if err != nil {
+			return false, err
+		}
+		err = queries.MarkScheduledChirpFailed(ctx, database.MarkScheduledChirpFailedParams{
+			ID:      scheduled.ID,
+			Failure: sql.NullString{String: publishFailure(publishErr), Valid: true},
+		})
+		if err != nil {
+			return false, err
+		}
+		return true, tx.Commit()
+	}
+
+	err = queries.DeletePublishedScheduledChirp(ctx, scheduled.ID)
+	if err != nil {
+		return false, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return false, err
+	}
+
+	_, err = cfg.announceChirp(ctx, chirp, mentionedIDs)
+	if err != nil {
+		util.ErrorLogger.Printf("Failed to announce scheduled chirp %s: %s", chirp.ID, err)
+	}
+	util.InfoLogger.Printf("Successfully published scheduled chirp: %s", chirp.ID)
+	return true, nil
+}
+
+func createScheduledChirp(ctx context.Context, queries *database.Queries, scheduled database.ScheduledChirp) (database.Chirp, []uuid.UUID, error) {
+	chirp, err := queries.CreateChirpWithID(ctx, database.CreateChirpWithIDParams{
+		ID:            scheduled.ID,
+		Body:          scheduled.Body,
+		UserID:        uuid.NullUUID{UUID: scheduled.UserID, Valid: true},
+		ParentID:      scheduled.ParentID,
+		QuotedChirpID: scheduled.QuotedChirpID,
+	})
+	if err != nil {
+		return database.Chirp{}, nil, err
+	}
+
+	err = attachChirpMedia(ctx, queries, chirp, scheduled.MediaIds)
+	if err != nil {
+		return database.Chirp{}, nil, err
+	}
+
+	mentionedIDs, err := storeChirpEntities(ctx, queries, chirp)
+	if err != nil {
+		return database.Chirp{}, nil, err
+	}
+	return chirp, mentionedIDs, nil
+}
+
+// publishFailure turns a publishing error into a message for the author.
+func publishFailure(err error) string {
+	switch {
+	case errors.Is(err, errMediaNotFound):
+		return errMediaNotFound.Error()
+	case isUniqueViolation(err):
+		return "Chirp has already been rechirped"
+	case isForeignKeyViolation(err):
+		return "Parent chirp not found"
+	default:
+		return "Failed to publish chirp"
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/pagination"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+)
+
+func (cfg *ApiConfig) ChirpSearchHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling chirp search.")
+
+	viewerID, err := cfg.optionalUserID(request)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Checking for query parameters.")
+	query := strings.TrimSpace(request.URL.Query().Get("q"))
+	if query == "" {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Search query is required", fmt.Errorf("Search query is empty"))
+		return
+	}
+
+	authorUUID, err := parseAuthorID(request)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid author_id", err)
+		return
+	}
+
+	limit, err := pagination.ParseLimit(request.URL.Query())
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	offset := 0
+	cursorString := request.URL.Query().Get("cursor")
+	if cursorString != "" {
+		offset, err = pagination.DecodeOffset(cursorString)
+		if err != nil {
+			util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	}
+
+	searchParams := database.SearchChirpsParams{
+		Query:      query,
+		AuthorID:   authorUUID,
+		PageLimit:  int32(limit + 1),
+		PageOffset: int32(offset),
+	}
+
+	util.InfoLogger.Printf("Searching chirps for: %s", query)
+	results, err := cfg.db.SearchChirps(request.Context(), searchParams)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to search chirps", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Generating response body from search results.")
+	responseBody := ChirpPage{Chirps: ChirpSlice{}}
+	if len(results) > limit {
+		results = results[:limit]
+		responseBody.NextCursor = pagination.EncodeOffset(offset + limit)
+	}
+	for _, result := range results {
+		responseBody.Chirps = append(responseBody.Chirps, chirpFromDatabase(result.Chirp))
+	}
+
+	err = cfg.hydrateChirps(request.Context(), responseBody.Chirps, viewerID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to load chirp details", err)
+		return
+	}
+
+	respondWithChirpPage(writer, request, responseBody)
+	util.InfoLogger.Printf("Successfully searched chirps.")
+}
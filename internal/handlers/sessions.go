@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/auth"
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+)
+
+// Session is a refresh token family: one login on one device, kept alive by
+// refreshing. Its ID is the family ID.
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	StartedAt  time.Time `json:"started_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// clientIP is the address the request came from. Chirpy does not sit behind
+// a proxy it trusts, so forwarding headers are ignored.
+func clientIP(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
+
+func (cfg *ApiConfig) SessionsReadHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling reading of sessions.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, sessionID, err := cfg.keyring.ValidateSessionJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Loading sessions of user: %s", userID)
+	dbSessions, err := cfg.db.ReadUserSessions(request.Context(), database.ReadUserSessionsParams{
+		UserID: userID,
+		Now:    time.Now().UTC(),
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read sessions", err)
+		return
+	}
+
+	responseBody := []Session{}
+	for _, dbSession := range dbSessions {
+		responseBody = append(responseBody, Session{
+			ID:         dbSession.FamilyID,
+			UserAgent:  dbSession.UserAgent,
+			IP:         dbSession.Ip,
+			StartedAt:  dbSession.StartedAt,
+			LastUsedAt: dbSession.LastUsedAt,
+			ExpiresAt:  dbSession.ExpiresAt,
+			Current:    sessionID.Valid && sessionID.UUID == dbSession.FamilyID,
+		})
+	}
+	util.RespondWithJson(writer, request, http.StatusOK, responseBody)
+	util.InfoLogger.Printf("Successfully read sessions.")
+}
+
+// SessionRevokeHandler logs a single session out. Access tokens already
+// issued for it stay valid until they expire.
+func (cfg *ApiConfig) SessionRevokeHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling revocation of session.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	sessionID, err := uuid.Parse(request.PathValue("sessionID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid session ID", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Revoking session %s of user %s", sessionID, userID)
+	rows, err := cfg.db.RevokeUserSession(request.Context(), database.RevokeUserSessionParams{
+		UserID:   userID,
+		FamilyID: sessionID,
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to revoke session", err)
+		return
+	}
+	if rows == 0 {
+		util.RespondWithError(writer, request, http.StatusNotFound, "Session not found", nil)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+	util.InfoLogger.Printf("Successfully revoked session.")
+}
+
+// SessionsRevokeAllHandler logs the user out everywhere, including the
+// session making the request.
+func (cfg *ApiConfig) SessionsRevokeAllHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling revocation of all sessions.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	tokenString, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Revoking all sessions of user %s", userID)
+	err = cfg.db.RevokeUserRefreshTokens(request.Context(), userID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to revoke sessions", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+	util.InfoLogger.Printf("Successfully revoked all sessions.")
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/broadcast"
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+)
+
+const (
+	ChirpCreatedEvent       = "chirp.created"
+	ChirpDeletedEvent       = "chirp.deleted"
+	ChirpRestoredEvent      = "chirp.restored"
+	StreamKeepAliveInterval = 15 * time.Second
+)
+
+// publishChirpEvent hands a chirp event to the in-process broadcaster. Like
+// notifications, this is a side effect and failures are only logged.
+func (cfg *ApiConfig) publishChirpEvent(eventType string, authorID uuid.UUID, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		util.ErrorLogger.Printf("Failed to marshal %s event: %s", eventType, err)
+		return
+	}
+	cfg.chirpEvents.Publish(eventType, authorID, data)
+}
+
+// publishChirp broadcasts a chirp as it looks to someone who is not logged
+// in, since the event reaches every subscriber and not only its author.
+func (cfg *ApiConfig) publishChirp(ctx context.Context, eventType string, dbChirp database.Chirp) {
+	chirps := ChirpSlice{chirpFromDatabase(dbChirp)}
+	err := cfg.hydrateChirps(ctx, chirps, uuid.NullUUID{})
+	if err != nil {
+		util.ErrorLogger.Printf("Failed to load details for %s event: %s", eventType, err)
+		return
+	}
+	cfg.publishChirpEvent(eventType, dbChirp.UserID.UUID, chirps[0])
+}
+
+func (cfg *ApiConfig) ChirpStreamHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling chirp stream.")
+
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Streaming is not supported", fmt.Errorf("ResponseWriter does not implement http.Flusher"))
+		return
+	}
+
+	util.InfoLogger.Printf("Checking for query parameters.")
+	authorUUID, err := parseAuthorID(request)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid author_id", err)
+		return
+	}
+
+	lastEventID := uint64(0)
+	lastEventIDString := request.Header.Get("Last-Event-ID")
+	if lastEventIDString != "" {
+		lastEventID, err = strconv.ParseUint(lastEventIDString, 10, 64)
+		if err != nil {
+			util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid Last-Event-ID", err)
+			return
+		}
+	}
+
+	var filter broadcast.Filter
+	if authorUUID.Valid {
+		filter = func(event broadcast.Event) bool {
+			return event.Key == authorUUID.UUID
+		}
+	}
+
+	subscription, replay := cfg.chirpEvents.Subscribe(lastEventID, filter)
+	defer cfg.chirpEvents.Unsubscribe(subscription)
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.Header().Set("Connection", "keep-alive")
+	writer.WriteHeader(http.StatusOK)
+
+	util.InfoLogger.Printf("Replaying %d chirp events after: %d", len(replay), lastEventID)
+	for _, event := range replay {
+		err = writeServerSentEvent(writer, event)
+		if err != nil {
+			util.ErrorLogger.Println(err)
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(StreamKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-request.Context().Done():
+			util.InfoLogger.Printf("Chirp stream client disconnected.")
+			return
+		case <-subscription.Evicted:
+			util.WarnLogger.Printf("Chirp stream client was too slow and has been evicted.")
+			return
+		case event := <-subscription.Events:
+			err = writeServerSentEvent(writer, event)
+			if err != nil {
+				util.ErrorLogger.Println(err)
+				return
+			}
+			flusher.Flush()
+		case <-keepAlive.C:
+			_, err = io.WriteString(writer, ": keep-alive\n\n")
+			if err != nil {
+				util.ErrorLogger.Println(err)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeServerSentEvent(writer io.Writer, event broadcast.Event) error {
+	_, err := fmt.Fprintf(writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+	return err
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/pagination"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+)
+
+type ChirpThread struct {
+	Ancestors   ChirpSlice `json:"ancestors"`
+	Chirp       Chirp      `json:"chirp"`
+	Descendants ChirpSlice `json:"descendants"`
+	NextCursor  string     `json:"next_cursor,omitempty"`
+}
+
+func (cfg *ApiConfig) ChirpRepliesReadHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling reading of chirp replies.")
+
+	viewerID, err := cfg.optionalUserID(request)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Reading request ChirpID.")
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid chirpID", err)
+		return
+	}
+
+	pageParams, err := pagination.ParseParams(request.URL.Query())
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	cursorCreatedAt, cursorID := cursorArgs(pageParams.Cursor)
+
+	_, err = cfg.db.ReadChirp(request.Context(), chirpID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusNotFound, "chirp not found", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Loading replies of chirp: %s", chirpID)
+	replies, err := cfg.db.ReadChirpReplies(request.Context(), database.ReadChirpRepliesParams{
+		ParentID:        chirpID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		PageLimit:       int32(pageParams.Limit + 1),
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read replies", err)
+		return
+	}
+
+	responseBody := newChirpPage(replies, pageParams.Limit)
+	err = cfg.hydrateChirps(request.Context(), responseBody.Chirps, viewerID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to load chirp details", err)
+		return
+	}
+
+	respondWithChirpPage(writer, request, responseBody)
+	util.InfoLogger.Printf("Successfully read chirp replies.")
+}
+
+func (cfg *ApiConfig) ChirpThreadReadHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling reading of chirp thread.")
+
+	viewerID, err := cfg.optionalUserID(request)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Reading request ChirpID.")
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid chirpID", err)
+		return
+	}
+
+	pageParams, err := pagination.ParseParams(request.URL.Query())
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	cursorCreatedAt, cursorID := cursorArgs(pageParams.Cursor)
+
+	dbChirp, err := cfg.db.ReadChirp(request.Context(), chirpID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusNotFound, "chirp not found", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Loading ancestors of chirp: %s", chirpID)
+	ancestors, err := cfg.db.ReadChirpAncestors(request.Context(), chirpID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read thread", err)
+		return
+	}
+
+	util.InfoLogger.Printf("Loading descendants of chirp: %s", chirpID)
+	descendants, err := cfg.db.ReadChirpDescendants(request.Context(), database.ReadChirpDescendantsParams{
+		RootID:          chirpID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		PageLimit:       int32(pageParams.Limit + 1),
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to read thread", err)
+		return
+	}
+
+	descendantPage := newChirpPage(descendants, pageParams.Limit)
+	responseBody := ChirpThread{
+		Ancestors:   ChirpSlice{},
+		Chirp:       chirpFromDatabase(dbChirp),
+		Descendants: descendantPage.Chirps,
+		NextCursor:  descendantPage.NextCursor,
+	}
+	for _, ancestor := range ancestors {
+		responseBody.Ancestors = append(responseBody.Ancestors, chirpFromDatabase(ancestor))
+	}
+
+	// Like counts for the whole thread are loaded with one query.
+	threadChirps := append(ChirpSlice{responseBody.Chirp}, responseBody.Ancestors...)
+	threadChirps = append(threadChirps, responseBody.Descendants...)
+	err = cfg.hydrateChirps(request.Context(), threadChirps, viewerID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to load chirp details", err)
+		return
+	}
+	responseBody.Chirp = threadChirps[0]
+	copy(responseBody.Ancestors, threadChirps[1:1+len(responseBody.Ancestors)])
+	copy(responseBody.Descendants, threadChirps[1+len(responseBody.Ancestors):])
+
+	util.RespondWithJson(writer, request, http.StatusOK, responseBody)
+	util.InfoLogger.Printf("Successfully read chirp thread.")
+}
@@ -1,13 +1,16 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/kwekkwekpatu/chirpy/internal/auth"
 	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/entities"
 	"github.com/kwekkwekpatu/chirpy/internal/util"
 	_ "github.com/lib/pq"
 )
@@ -17,13 +20,27 @@ type User struct {
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
 	Email      string    `json:"email"`
+	Username   string    `json:"username,omitempty"`
 	ChirpIsRed bool      `json:"is_chirpy_red"`
 }
 
+// parseUsername normalizes an optional username. An empty username is NULL.
+func parseUsername(username string) (sql.NullString, error) {
+	if username == "" {
+		return sql.NullString{}, nil
+	}
+	normalizedUsername, ok := entities.NormalizeUsername(username)
+	if !ok {
+		return sql.NullString{}, fmt.Errorf("Invalid username: %s", username)
+	}
+	return sql.NullString{String: normalizedUsername, Valid: true}, nil
+}
+
 func (cfg *ApiConfig) UserHandler(writer http.ResponseWriter, request *http.Request) {
 	type parameters struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Username string `json:"username"`
 	}
 
 	util.InfoLogger.Printf("Handling user creation.")
@@ -45,6 +62,12 @@ func (cfg *ApiConfig) UserHandler(writer http.ResponseWriter, request *http.Requ
 		return
 	}
 
+	username, err := parseUsername(params.Username)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Username must be 3 to 30 letters, digits or underscores", err)
+		return
+	}
+
 	util.InfoLogger.Printf("Processing password")
 	hashedPassword, err := auth.HashPassword(params.Password)
 	if err != nil {
@@ -54,10 +77,15 @@ func (cfg *ApiConfig) UserHandler(writer http.ResponseWriter, request *http.Requ
 	userParams := database.CreateUserParams{
 		Email:          params.Email,
 		HashedPassword: hashedPassword,
+		Username:       username,
 	}
 
 	util.InfoLogger.Printf("Attempting to create user with email: %s", params.Email)
 	user, err := cfg.db.CreateUser(request.Context(), userParams)
+	if isUniqueViolation(err) {
+		util.RespondWithError(writer, request, http.StatusConflict, "Email or username is already taken", err)
+		return
+	}
 	if err != nil {
 		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to create user.", err)
 		return
@@ -65,17 +93,21 @@ func (cfg *ApiConfig) UserHandler(writer http.ResponseWriter, request *http.Requ
 	util.InfoLogger.Printf("Successfully created a user for email: %s", params.Email)
 
 	util.InfoLogger.Printf("Generating response body from user.")
-	responseBody := User{ID: user.ID, CreatedAt: user.CreatedAt, UpdatedAt: user.UpdatedAt, Email: user.Email, ChirpIsRed: user.IsChirpyRed}
+	responseBody := User{ID: user.ID, CreatedAt: user.CreatedAt, UpdatedAt: user.UpdatedAt, Email: user.Email, Username: user.Username.String, ChirpIsRed: user.IsChirpyRed}
 	util.RespondWithJson(writer, request, http.StatusCreated, responseBody)
 
 	util.InfoLogger.Printf("Successfully created user.")
 	return
 }
 
+// UpdateUserPasswordHandler updates the email and password of a user, and
+// their username when one is given. Leaving the username out keeps it. Other
+// sessions and all passkeys of the user are revoked.
 func (cfg *ApiConfig) UpdateUserPasswordHandler(writer http.ResponseWriter, request *http.Request) {
 	type parameters struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Username string `json:"username"`
 	}
 
 	util.InfoLogger.Printf("Entering password handler.")
@@ -87,7 +119,7 @@ func (cfg *ApiConfig) UpdateUserPasswordHandler(writer http.ResponseWriter, requ
 		return
 	}
 
-	userID, err := auth.ValidateJWT(tokenString, cfg.jwtSecret)
+	userID, sessionID, err := cfg.keyring.ValidateSessionJWT(tokenString)
 	if err != nil {
 		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
 		return
@@ -112,6 +144,12 @@ func (cfg *ApiConfig) UpdateUserPasswordHandler(writer http.ResponseWriter, requ
 		return
 	}
 
+	username, err := parseUsername(params.Username)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Username must be 3 to 30 letters, digits or underscores", err)
+		return
+	}
+
 	util.InfoLogger.Printf("Processing new password")
 	hashedPassword, err := auth.HashPassword(params.Password)
 	if err != nil {
@@ -122,10 +160,50 @@ func (cfg *ApiConfig) UpdateUserPasswordHandler(writer http.ResponseWriter, requ
 		ID:             userID,
 		Email:          params.Email,
 		HashedPassword: hashedPassword,
+		Username:       username,
+	}
+
+	tx, err := cfg.dbConn.BeginTx(request.Context(), nil)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to update password.", err)
+		return
 	}
+	defer tx.Rollback()
+	queries := cfg.db.WithTx(tx)
 
 	util.InfoLogger.Printf("Attempting to update password with email: %s", params.Email)
-	user, err := cfg.db.PutPasswordByUser(request.Context(), userParams)
+	user, err := queries.PutPasswordByUser(request.Context(), userParams)
+	if isUniqueViolation(err) {
+		util.RespondWithError(writer, request, http.StatusConflict, "Email or username is already taken", err)
+		return
+	}
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to update password.", err)
+		return
+	}
+
+	// Whoever knew the old password may have logged in elsewhere, so only the
+	// session that changed the password survives.
+	util.InfoLogger.Printf("Revoking other sessions of user %s", userID)
+	err = queries.RevokeOtherUserRefreshTokens(request.Context(), database.RevokeOtherUserRefreshTokensParams{
+		UserID:   userID,
+		FamilyID: sessionID,
+	})
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to revoke other sessions.", err)
+		return
+	}
+
+	// A passkey registered by someone who knew the old password would let
+	// them back in, so the user registers theirs again.
+	util.InfoLogger.Printf("Revoking passkeys of user %s", userID)
+	err = queries.DeletePasskeysByUser(request.Context(), userID)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to revoke passkeys.", err)
+		return
+	}
+
+	err = tx.Commit()
 	if err != nil {
 		util.RespondWithError(writer, request, http.StatusInternalServerError, "Failed to update password.", err)
 		return
@@ -133,7 +211,7 @@ func (cfg *ApiConfig) UpdateUserPasswordHandler(writer http.ResponseWriter, requ
 	util.InfoLogger.Printf("Successfully updated password for email: %s", params.Email)
 
 	util.InfoLogger.Printf("Generating response body from user.")
-	responseBody := User{ID: user.ID, CreatedAt: user.CreatedAt, UpdatedAt: user.UpdatedAt, Email: user.Email, ChirpIsRed: user.IsChirpyRed}
+	responseBody := User{ID: user.ID, CreatedAt: user.CreatedAt, UpdatedAt: user.UpdatedAt, Email: user.Email, Username: user.Username.String, ChirpIsRed: user.IsChirpyRed}
 	util.RespondWithJson(writer, request, http.StatusOK, responseBody)
 
 	util.InfoLogger.Printf("Successfully updated password.")
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+)
+
+// FieldError describes what is wrong with a single request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+type ValidationErrorResponse struct {
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields"`
+}
+
+// chirpInput is a chirp as submitted by its author, before validation.
+type chirpInput struct {
+	Body          string
+	ParentID      *uuid.UUID
+	QuotedChirpID *uuid.UUID
+	MediaIDs      []uuid.UUID
+}
+
+// validateChirp runs every check a new chirp has to pass and returns the
+// parameters to create it with. All problems are reported, in the order the
+// checks ran, so callers can either show them all or only the first.
+func (cfg *ApiConfig) validateChirp(ctx context.Context, userID uuid.UUID, input chirpInput) (database.CreateChirpParams, []FieldError) {
+	fieldErrors := []FieldError{}
+	chirpParams := database.CreateChirpParams{
+		UserID: uuid.NullUUID{UUID: userID, Valid: true},
+	}
+
+	cleanedBody, err := cfg.prepareChirpBody(input.Body)
+	if err != nil {
+		fieldErrors = append(fieldErrors, FieldError{Field: "body", Message: err.Error()})
+	}
+	chirpParams.Body = cleanedBody
+
+	rechirp := input.QuotedChirpID != nil && input.Body == ""
+	if rechirp && input.ParentID != nil {
+		fieldErrors = append(fieldErrors, FieldError{Field: "parent_id", Message: "A rechirp cannot be a reply"})
+	}
+
+	if rechirp && len(input.MediaIDs) > 0 {
+		fieldErrors = append(fieldErrors, FieldError{Field: "media_ids", Message: "A rechirp cannot have media"})
+	} else if err := validateMediaIDs(input.MediaIDs); err != nil {
+		fieldErrors = append(fieldErrors, FieldError{Field: "media_ids", Message: err.Error()})
+	}
+
+	if input.ParentID != nil && !rechirp {
+		util.InfoLogger.Printf("Checking if parent chirp exists: %s", *input.ParentID)
+		_, err := cfg.db.ReadChirp(ctx, *input.ParentID)
+		if err != nil {
+			util.ErrorLogger.Println(err)
+			fieldErrors = append(fieldErrors, FieldError{Field: "parent_id", Message: "Parent chirp not found"})
+		}
+		chirpParams.ParentID = uuid.NullUUID{UUID: *input.ParentID, Valid: true}
+	}
+
+	if input.QuotedChirpID != nil {
+		util.InfoLogger.Printf("Checking if quoted chirp exists: %s", *input.QuotedChirpID)
+		quotedChirp, err := cfg.db.ReadChirp(ctx, *input.QuotedChirpID)
+		if err != nil {
+			util.ErrorLogger.Println(err)
+			fieldErrors = append(fieldErrors, FieldError{Field: "quoted_chirp_id", Message: "Quoted chirp not found"})
+		} else {
+			// Rechirping a rechirp points at the original instead.
+			if isRechirp(quotedChirp) {
+				quotedChirp.ID = quotedChirp.QuotedChirpID.UUID
+			}
+			chirpParams.QuotedChirpID = uuid.NullUUID{UUID: quotedChirp.ID, Valid: true}
+		}
+	}
+
+	return chirpParams, fieldErrors
+}
@@ -0,0 +1,357 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/auth"
+	"github.com/kwekkwekpatu/chirpy/internal/broadcast"
+	"github.com/kwekkwekpatu/chirpy/internal/entities"
+	"github.com/kwekkwekpatu/chirpy/internal/util"
+	"github.com/kwekkwekpatu/chirpy/internal/websocket"
+)
+
+const (
+	WebSocketPingInterval = 30 * time.Second
+	WebSocketPongWait     = 60 * time.Second
+	WebSocketWriteWait    = 10 * time.Second
+	WebSocketSendBuffer   = 64
+	WebSocketMaxChannels  = 16
+)
+
+// Browser clients authenticate by offering WebSocketProtocol together with
+// WebSocketTokenPrefix followed by their access token, for example
+// new WebSocket(url, ["chirpy", "chirpy.bearer." + token]). The server only
+// echoes WebSocketProtocol, never the token.
+const (
+	WebSocketProtocol    = "chirpy"
+	WebSocketTokenPrefix = "chirpy.bearer."
+)
+
+const (
+	ChannelTimeline      = "timeline"
+	ChannelNotifications = "notifications"
+	ChannelUserPrefix    = "user:"
+	ChannelHashtagPrefix = "hashtag:"
+)
+
+type webSocketRequest struct {
+	Action  string `json:"action"`
+	Channel string `json:"channel"`
+}
+
+type webSocketMessage struct {
+	Type    string          `json:"type"`
+	Channel string          `json:"channel,omitempty"`
+	ID      uint64          `json:"id,omitempty"`
+	Event   string          `json:"event,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Message string          `json:"message,omitempty"`
+}
+
+// webSocketSession owns one connection. All writes except the final close
+// frame go through send, which is drained by a single writer; a client that
+// lets it fill up is disconnected instead of slowing down publishers.
+type webSocketSession struct {
+	ctx    context.Context
+	cfg    *ApiConfig
+	conn   *websocket.Conn
+	userID uuid.UUID
+	send   chan webSocketMessage
+	done   chan struct{}
+
+	endOnce  sync.Once
+	mu       sync.Mutex
+	channels map[string]func()
+}
+
+func (cfg *ApiConfig) WebSocketHandler(writer http.ResponseWriter, request *http.Request) {
+	util.InfoLogger.Printf("Handling websocket connection.")
+
+	util.InfoLogger.Printf("Extracting and validating JWT token")
+	// Browsers cannot set headers on websocket requests, but they can offer
+	// subprotocols, so the token may also be passed as one. Unlike a query
+	// parameter it does not end up in access logs.
+	protocol := ""
+	tokenString := ""
+	for _, offered := range websocket.Subprotocols(request) {
+		if strings.HasPrefix(offered, WebSocketTokenPrefix) {
+			tokenString = strings.TrimPrefix(offered, WebSocketTokenPrefix)
+			protocol = WebSocketProtocol
+		}
+	}
+	if tokenString == "" {
+		var err error
+		tokenString, err = auth.GetBearerToken(request.Header)
+		if err != nil {
+			util.RespondWithError(writer, request, http.StatusUnauthorized, "Missing authorization header.", err)
+			return
+		}
+	}
+
+	userID, expiresAt, err := cfg.keyring.ValidateJWTWithExpiry(tokenString)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusUnauthorized, "JWT is invalid", err)
+		return
+	}
+
+	conn, err := websocket.UpgradeWithSubprotocol(writer, request, protocol)
+	if err != nil {
+		util.RespondWithError(writer, request, http.StatusBadRequest, "Invalid websocket handshake", err)
+		return
+	}
+	defer conn.Close()
+
+	session := &webSocketSession{
+		ctx:      request.Context(),
+		cfg:      cfg,
+		conn:     conn,
+		userID:   userID,
+		send:     make(chan webSocketMessage, WebSocketSendBuffer),
+		done:     make(chan struct{}),
+		channels: map[string]func(){},
+	}
+	defer session.unsubscribeAll()
+
+	util.InfoLogger.Printf("Websocket connected for user: %s", userID)
+	go session.readLoop()
+	session.writeLoop(expiresAt)
+	util.InfoLogger.Printf("Websocket disconnected for user: %s", userID)
+}
+
+// end closes the session once with the given close code.
+func (s *webSocketSession) end(code int, reason string) {
+	s.endOnce.Do(func() {
+		s.conn.SetWriteDeadline(time.Now().Add(WebSocketWriteWait))
+		s.conn.WriteClose(code, reason)
+		close(s.done)
+	})
+}
+
+// enqueue hands a message to the writer without blocking.
+func (s *webSocketSession) enqueue(message webSocketMessage) {
+	select {
+	case s.send <- message:
+	case <-s.done:
+	default:
+		util.WarnLogger.Printf("Websocket client %s is too slow, disconnecting.", s.userID)
+		s.end(websocket.CloseTryAgainLater, "Client is too slow")
+	}
+}
+
+func (s *webSocketSession) writeLoop(expiresAt time.Time) {
+	ping := time.NewTicker(WebSocketPingInterval)
+	defer ping.Stop()
+	expiry := time.NewTimer(time.Until(expiresAt))
+	defer expiry.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-expiry.C:
+			util.InfoLogger.Printf("Access token expired for websocket user: %s", s.userID)
+			s.end(websocket.ClosePolicyViolation, "Access token expired")
+			return
+		case <-ping.C:
+			s.conn.SetWriteDeadline(time.Now().Add(WebSocketWriteWait))
+			err := s.conn.WriteMessage(websocket.OpPing, nil)
+			if err != nil {
+				util.ErrorLogger.Println(err)
+				s.end(websocket.CloseGoingAway, "")
+				return
+			}
+		case message := <-s.send:
+			data, err := json.Marshal(message)
+			if err != nil {
+				util.ErrorLogger.Println(err)
+				continue
+			}
+			s.conn.SetWriteDeadline(time.Now().Add(WebSocketWriteWait))
+			err = s.conn.WriteMessage(websocket.OpText, data)
+			if err != nil {
+				util.ErrorLogger.Println(err)
+				s.end(websocket.CloseGoingAway, "")
+				return
+			}
+		}
+	}
+}
+
+func (s *webSocketSession) readLoop() {
+	for {
+		// Any frame, including the pong to our ping, proves the client is
+		// still there.
+		s.conn.SetReadDeadline(time.Now().Add(WebSocketPongWait))
+		opcode, payload, err := s.conn.ReadMessage()
+		if err != nil {
+			s.end(websocket.CloseGoingAway, "")
+			return
+		}
+
+		switch opcode {
+		case websocket.OpPong:
+			continue
+		case websocket.OpBinary:
+			s.end(websocket.CloseUnsupportedData, "Only text messages are supported")
+			return
+		}
+
+		params := webSocketRequest{}
+		err = json.Unmarshal(payload, &params)
+		if err != nil {
+			s.enqueue(webSocketMessage{Type: "error", Message: "Couldn't decode message"})
+			continue
+		}
+
+		switch params.Action {
+		case "subscribe":
+			err = s.subscribe(params.Channel)
+		case "unsubscribe":
+			err = s.unsubscribe(params.Channel)
+		case "ping":
+			s.enqueue(webSocketMessage{Type: "pong"})
+		default:
+			err = fmt.Errorf("Unknown action")
+		}
+		if err != nil {
+			s.enqueue(webSocketMessage{Type: "error", Channel: params.Channel, Message: err.Error()})
+		}
+	}
+}
+
+// channelSource resolves a channel name to the broadcaster and filter that
+// back it, and to a release func, which may be nil, to call on unsubscribe.
+func (s *webSocketSession) channelSource(channel string) (*broadcast.Broadcaster, broadcast.Filter, func(), error) {
+	switch {
+	case channel == ChannelNotifications:
+		return s.cfg.notificationEvents, func(event broadcast.Event) bool {
+			return event.Key == s.userID
+		}, nil, nil
+
+	case channel == ChannelTimeline:
+		// The set is registered before it is filled, so a follow or unfollow
+		// that commits while the followees are read is not lost.
+		followees := s.cfg.timelineFollowees.register(s.userID)
+		followeeIDs, err := s.cfg.db.ReadFolloweeIDs(s.ctx, s.userID)
+		if err != nil {
+			s.cfg.timelineFollowees.unregister(s.userID, followees)
+			util.ErrorLogger.Println(err)
+			return nil, nil, nil, fmt.Errorf("Failed to read followed users")
+		}
+		followees.fill(followeeIDs)
+		release := func() {
+			s.cfg.timelineFollowees.unregister(s.userID, followees)
+		}
+		return s.cfg.chirpEvents, func(event broadcast.Event) bool {
+			return followees.contains(event.Key)
+		}, release, nil
+
+	case strings.HasPrefix(channel, ChannelUserPrefix):
+		userID, err := uuid.Parse(strings.TrimPrefix(channel, ChannelUserPrefix))
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("Invalid userID")
+		}
+		return s.cfg.chirpEvents, func(event broadcast.Event) bool {
+			return event.Key == userID
+		}, nil, nil
+
+	case strings.HasPrefix(channel, ChannelHashtagPrefix):
+		tag, ok := entities.NormalizeHashtag(strings.TrimPrefix(channel, ChannelHashtagPrefix))
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("Invalid hashtag")
+		}
+		return s.cfg.chirpEvents, func(event broadcast.Event) bool {
+			if event.Type != ChirpCreatedEvent {
+				return false
+			}
+			chirp := struct {
+				Body string `json:"body"`
+			}{}
+			err := json.Unmarshal(event.Data, &chirp)
+			return err == nil && slices.Contains(entities.Hashtags(chirp.Body), tag)
+		}, nil, nil
+	}
+	return nil, nil, nil, fmt.Errorf("Unknown channel")
+}
+
+func (s *webSocketSession) subscribe(channel string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.channels[channel]; ok {
+		return fmt.Errorf("Already subscribed")
+	}
+	if len(s.channels) >= WebSocketMaxChannels {
+		return fmt.Errorf("Too many subscriptions")
+	}
+
+	source, filter, release, err := s.channelSource(channel)
+	if err != nil {
+		return err
+	}
+
+	subscription, _ := source.Subscribe(0, filter)
+	stop := make(chan struct{})
+	s.channels[channel] = func() {
+		close(stop)
+		source.Unsubscribe(subscription)
+		if release != nil {
+			release()
+		}
+	}
+
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-s.done:
+				return
+			case <-subscription.Evicted:
+				util.WarnLogger.Printf("Websocket client %s was evicted from %s.", s.userID, channel)
+				s.end(websocket.CloseTryAgainLater, "Client is too slow")
+				return
+			case event := <-subscription.Events:
+				s.enqueue(webSocketMessage{Type: "event", Channel: channel, ID: event.ID, Event: event.Type, Data: event.Data})
+			}
+		}
+	}()
+
+	util.InfoLogger.Printf("Websocket user %s subscribed to %s", s.userID, channel)
+	s.enqueue(webSocketMessage{Type: "subscribed", Channel: channel})
+	return nil
+}
+
+func (s *webSocketSession) unsubscribe(channel string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cancel, ok := s.channels[channel]
+	if !ok {
+		return fmt.Errorf("Not subscribed")
+	}
+	cancel()
+	delete(s.channels, channel)
+
+	util.InfoLogger.Printf("Websocket user %s unsubscribed from %s", s.userID, channel)
+	s.enqueue(webSocketMessage{Type: "unsubscribed", Channel: channel})
+	return nil
+}
+
+func (s *webSocketSession) unsubscribeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for channel, cancel := range s.channels {
+		cancel()
+		delete(s.channels, channel)
+	}
+}
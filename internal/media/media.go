@@ -0,0 +1,225 @@
+// Package media validates uploaded images and prepares them for storage.
+// Images are re-encoded, which drops EXIF and every other kind of metadata.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	MaxUploadSize    = 5 << 20
+	MaxPixels        = 25_000_000
+	MaxFrames        = 500
+	ThumbnailSize    = 320
+	JPEGQuality      = 90
+	ContentTypeJPEG  = "image/jpeg"
+	ContentTypePNG   = "image/png"
+	ContentTypeGIF   = "image/gif"
+	maxSamplesPerDim = 4
+)
+
+var (
+	ErrUnsupportedType = errors.New("Unsupported image type")
+	ErrTooLarge        = errors.New("Image is too large")
+)
+
+// Image is an encoded image ready to be stored.
+type Image struct {
+	ContentType string
+	Data        []byte
+	Width       int
+	Height      int
+}
+
+// Processed holds the cleaned original and its thumbnail.
+type Processed struct {
+	Original  Image
+	Thumbnail Image
+}
+
+// Process sniffs the type of data, re-encodes it without metadata and
+// generates a thumbnail that fits in ThumbnailSize x ThumbnailSize. The
+// declared content type of an upload is never trusted.
+func Process(data []byte) (Processed, error) {
+	if len(data) > MaxUploadSize {
+		return Processed{}, ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if contentType != ContentTypeJPEG && contentType != ContentTypePNG && contentType != ContentTypeGIF {
+		return Processed{}, ErrUnsupportedType
+	}
+
+	// Check the dimensions before decoding to avoid decompression bombs.
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, fmt.Errorf("Invalid image: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+		return Processed{}, ErrTooLarge
+	}
+
+	switch contentType {
+	case ContentTypeJPEG:
+		return processJPEG(data)
+	case ContentTypePNG:
+		return processPNG(data)
+	default:
+		return processGIF(data, config)
+	}
+}
+
+func processJPEG(data []byte) (Processed, error) {
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, fmt.Errorf("Invalid image: %w", err)
+	}
+	// The orientation lives in the EXIF data we are about to drop, so it has
+	// to be applied to the pixels.
+	img = applyOrientation(img, exifOrientation(data))
+
+	original, err := encodeJPEG(img)
+	if err != nil {
+		return Processed{}, err
+	}
+	thumbnail, err := encodeJPEG(thumbnail(img))
+	if err != nil {
+		return Processed{}, err
+	}
+	return Processed{Original: original, Thumbnail: thumbnail}, nil
+}
+
+func processPNG(data []byte) (Processed, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, fmt.Errorf("Invalid image: %w", err)
+	}
+
+	original, err := encodePNG(img)
+	if err != nil {
+		return Processed{}, err
+	}
+	thumbnail, err := encodePNG(thumbnail(img))
+	if err != nil {
+		return Processed{}, err
+	}
+	return Processed{Original: original, Thumbnail: thumbnail}, nil
+}
+
+func processGIF(data []byte, config image.Config) (Processed, error) {
+	// DecodeAll decodes every frame, so the frames are counted first.
+	frames, err := countGIFFrames(data)
+	if err != nil {
+		return Processed{}, fmt.Errorf("Invalid image: %w", err)
+	}
+	if frames > MaxFrames || frames*config.Width*config.Height > MaxPixels*4 {
+		return Processed{}, ErrTooLarge
+	}
+
+	animation, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, fmt.Errorf("Invalid image: %w", err)
+	}
+
+	// EncodeAll only writes frames, timing and loop count; comments and
+	// application extensions are not preserved.
+	buffer := bytes.Buffer{}
+	err = gif.EncodeAll(&buffer, animation)
+	if err != nil {
+		return Processed{}, err
+	}
+	original := Image{ContentType: ContentTypeGIF, Data: buffer.Bytes(), Width: config.Width, Height: config.Height}
+
+	// Frames may only cover part of the canvas, so the thumbnail is taken
+	// from the first frame drawn onto the full canvas.
+	canvas := image.NewRGBA(image.Rect(0, 0, config.Width, config.Height))
+	draw.Draw(canvas, animation.Image[0].Bounds(), animation.Image[0], animation.Image[0].Bounds().Min, draw.Over)
+	thumbnail, err := encodePNG(thumbnail(canvas))
+	if err != nil {
+		return Processed{}, err
+	}
+	return Processed{Original: original, Thumbnail: thumbnail}, nil
+}
+
+// countGIFFrames walks the blocks of a GIF and counts its image descriptors
+// without decompressing any of them.
+func countGIFFrames(data []byte) (int, error) {
+	const headerSize = 13
+	if len(data) < headerSize {
+		return 0, errors.New("gif: truncated header")
+	}
+	pos := headerSize
+	if flags := data[10]; flags&0x80 != 0 {
+		pos += 3 << (flags&0x07 + 1)
+	}
+
+	frames := 0
+	for {
+		if pos >= len(data) {
+			return 0, errors.New("gif: missing trailer")
+		}
+		introducer := data[pos]
+		pos++
+		switch introducer {
+		case 0x21:
+			// Extension: a label followed by data sub-blocks.
+			pos++
+		case 0x2c:
+			// Image descriptor, optional local color table and the minimum
+			// LZW code size, followed by the image data sub-blocks.
+			if pos+9 > len(data) {
+				return 0, errors.New("gif: truncated image descriptor")
+			}
+			flags := data[pos+8]
+			pos += 9
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1)
+			}
+			pos++
+			frames++
+		case 0x3b:
+			return frames, nil
+		default:
+			return 0, fmt.Errorf("gif: unknown block type 0x%02x", introducer)
+		}
+
+		for {
+			if pos >= len(data) {
+				return 0, errors.New("gif: truncated data sub-block")
+			}
+			size := int(data[pos])
+			pos += 1 + size
+			if size == 0 {
+				break
+			}
+		}
+	}
+}
+
+func encodeJPEG(img image.Image) (Image, error) {
+	buffer := bytes.Buffer{}
+	err := jpeg.Encode(&buffer, img, &jpeg.Options{Quality: JPEGQuality})
+	if err != nil {
+		return Image{}, err
+	}
+	bounds := img.Bounds()
+	return Image{ContentType: ContentTypeJPEG, Data: buffer.Bytes(), Width: bounds.Dx(), Height: bounds.Dy()}, nil
+}
+
+func encodePNG(img image.Image) (Image, error) {
+	buffer := bytes.Buffer{}
+	err := png.Encode(&buffer, img)
+	if err != nil {
+		return Image{}, err
+	}
+	bounds := img.Bounds()
+	return Image{ContentType: ContentTypePNG, Data: buffer.Bytes(), Width: bounds.Dx(), Height: bounds.Dy()}, nil
+}
@@ -0,0 +1,258 @@
+package media_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/kwekkwekpatu/chirpy/internal/media"
+)
+
+func TestProcess(t *testing.T) {
+	testCases := []struct {
+		name                string
+		data                []byte
+		expectedType        string
+		expectedWidth       int
+		expectedHeight      int
+		expectedThumbWidth  int
+		expectedThumbHeight int
+	}{
+		{
+			name:                "landscape png",
+			data:                encodePNG(t, 1000, 500),
+			expectedType:        media.ContentTypePNG,
+			expectedWidth:       1000,
+			expectedHeight:      500,
+			expectedThumbWidth:  320,
+			expectedThumbHeight: 160,
+		},
+		{
+			name:                "portrait jpeg",
+			data:                encodeJPEG(t, 400, 800),
+			expectedType:        media.ContentTypeJPEG,
+			expectedWidth:       400,
+			expectedHeight:      800,
+			expectedThumbWidth:  160,
+			expectedThumbHeight: 320,
+		},
+		{
+			name:                "small gif is not upscaled",
+			data:                encodeGIF(t, 100, 50),
+			expectedType:        media.ContentTypeGIF,
+			expectedWidth:       100,
+			expectedHeight:      50,
+			expectedThumbWidth:  100,
+			expectedThumbHeight: 50,
+		},
+		{
+			name:                "animated gif",
+			data:                encodeAnimatedGIF(t, 3),
+			expectedType:        media.ContentTypeGIF,
+			expectedWidth:       2,
+			expectedHeight:      2,
+			expectedThumbWidth:  2,
+			expectedThumbHeight: 2,
+		},
+		{
+			name:                "exif orientation is applied",
+			data:                withExif(encodeJPEG(t, 400, 200), 6),
+			expectedType:        media.ContentTypeJPEG,
+			expectedWidth:       200,
+			expectedHeight:      400,
+			expectedThumbWidth:  160,
+			expectedThumbHeight: 320,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			processed, err := media.Process(tc.data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if processed.Original.ContentType != tc.expectedType {
+				t.Errorf("expected content type %s, got %s", tc.expectedType, processed.Original.ContentType)
+			}
+			if processed.Original.Width != tc.expectedWidth || processed.Original.Height != tc.expectedHeight {
+				t.Errorf("expected %dx%d, got %dx%d", tc.expectedWidth, tc.expectedHeight, processed.Original.Width, processed.Original.Height)
+			}
+			if processed.Thumbnail.Width != tc.expectedThumbWidth || processed.Thumbnail.Height != tc.expectedThumbHeight {
+				t.Errorf("expected thumbnail %dx%d, got %dx%d", tc.expectedThumbWidth, tc.expectedThumbHeight, processed.Thumbnail.Width, processed.Thumbnail.Height)
+			}
+
+			config, _, err := image.DecodeConfig(bytes.NewReader(processed.Thumbnail.Data))
+			if err != nil {
+				t.Fatalf("thumbnail does not decode: %v", err)
+			}
+			if config.Width != tc.expectedThumbWidth || config.Height != tc.expectedThumbHeight {
+				t.Errorf("encoded thumbnail is %dx%d", config.Width, config.Height)
+			}
+		})
+	}
+}
+
+func TestProcessStripsExif(t *testing.T) {
+	data := withExif(encodeJPEG(t, 64, 64), 1)
+	if !bytes.Contains(data, []byte("Exif")) {
+		t.Fatal("test image has no EXIF data")
+	}
+
+	processed, err := media.Process(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bytes.Contains(processed.Original.Data, []byte("Exif")) {
+		t.Error("EXIF data was not stripped from the original")
+	}
+	if bytes.Contains(processed.Thumbnail.Data, []byte("Exif")) {
+		t.Error("EXIF data was not stripped from the thumbnail")
+	}
+}
+
+func TestProcessRejectsInvalidUploads(t *testing.T) {
+	testCases := []struct {
+		name          string
+		data          []byte
+		expectedError error
+	}{
+		{
+			name:          "plain text",
+			data:          []byte("definitely not an image"),
+			expectedError: media.ErrUnsupportedType,
+		},
+		{
+			name:          "html",
+			data:          []byte("<html><body>hi</body></html>"),
+			expectedError: media.ErrUnsupportedType,
+		},
+		{
+			name:          "too many bytes",
+			data:          make([]byte, media.MaxUploadSize+1),
+			expectedError: media.ErrTooLarge,
+		},
+		{
+			name:          "too many pixels",
+			data:          pngHeader(10000, 10000),
+			expectedError: media.ErrTooLarge,
+		},
+		{
+			name: "truncated png",
+			data: encodePNG(t, 100, 100)[:60],
+		},
+		{
+			name:          "too many gif frames",
+			data:          encodeAnimatedGIF(t, media.MaxFrames+1),
+			expectedError: media.ErrTooLarge,
+		},
+		{
+			name: "truncated gif",
+			data: encodeAnimatedGIF(t, 3)[:40],
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := media.Process(tc.data)
+			if err == nil {
+				t.Fatal("expected error but got none")
+			}
+			if tc.expectedError != nil && !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected %v, got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func testImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	buffer := bytes.Buffer{}
+	err := png.Encode(&buffer, testImage(width, height))
+	if err != nil {
+		t.Fatalf("Failed to encode png: %v", err)
+	}
+	return buffer.Bytes()
+}
+
+func encodeJPEG(t *testing.T, width, height int) []byte {
+	buffer := bytes.Buffer{}
+	err := jpeg.Encode(&buffer, testImage(width, height), nil)
+	if err != nil {
+		t.Fatalf("Failed to encode jpeg: %v", err)
+	}
+	return buffer.Bytes()
+}
+
+func encodeGIF(t *testing.T, width, height int) []byte {
+	buffer := bytes.Buffer{}
+	err := gif.Encode(&buffer, testImage(width, height), nil)
+	if err != nil {
+		t.Fatalf("Failed to encode gif: %v", err)
+	}
+	return buffer.Bytes()
+}
+
+func encodeAnimatedGIF(t *testing.T, frames int) []byte {
+	animation := &gif.GIF{}
+	for i := 0; i < frames; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 2, 2), color.Palette{color.Black, color.White})
+		frame.SetColorIndex(i%2, 0, 1)
+		animation.Image = append(animation.Image, frame)
+		animation.Delay = append(animation.Delay, 10)
+	}
+	buffer := bytes.Buffer{}
+	err := gif.EncodeAll(&buffer, animation)
+	if err != nil {
+		t.Fatalf("Failed to encode gif: %v", err)
+	}
+	return buffer.Bytes()
+}
+
+// withExif inserts an APP1 segment with a little endian TIFF header holding
+// only an orientation tag right after the SOI marker.
+func withExif(jpegData []byte, orientation uint16) []byte {
+	tiff := []byte("II*\x00")
+	tiff = binary.LittleEndian.AppendUint32(tiff, 8)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, jpegData[:2]...)
+	out = append(out, segment...)
+	return append(out, jpegData[2:]...)
+}
+
+// pngHeader returns just enough of a PNG for its dimensions to be read.
+func pngHeader(width, height int) []byte {
+	buffer := bytes.Buffer{}
+	png.Encode(&buffer, image.NewGray(image.Rect(0, 0, 1, 1)))
+	data := buffer.Bytes()
+	binary.BigEndian.PutUint32(data[16:], uint32(width))
+	binary.BigEndian.PutUint32(data[20:], uint32(height))
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
@@ -0,0 +1,156 @@
+package media
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+)
+
+const exifOrientationTag = 0x0112
+
+// exifOrientation returns the EXIF orientation (1-8) of a JPEG, or 1 when it
+// has none.
+func exifOrientation(data []byte) int {
+	offset := 2 // Skip the SOI marker.
+	for offset+4 <= len(data) {
+		if data[offset] != 0xFF {
+			return 1
+		}
+		marker := data[offset+1]
+		// Start of scan: no more metadata follows.
+		if marker == 0xDA {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[offset+2:]))
+		segmentEnd := offset + 2 + length
+		if length < 2 || segmentEnd > len(data) {
+			return 1
+		}
+		segment := data[offset+4 : segmentEnd]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(segment[6:])
+		}
+		offset = segmentEnd
+	}
+	return 1
+}
+
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifdOffset := int(order.Uint32(tiff[4:]))
+	if ifdOffset+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifdOffset:]))
+	for i := 0; i < entries; i++ {
+		entry := ifdOffset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == exifOrientationTag {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// applyOrientation transforms img so it displays upright without the EXIF
+// orientation tag.
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation == 1 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	outWidth, outHeight := width, height
+	if orientation >= 5 {
+		outWidth, outHeight = height, width
+	}
+	out := image.NewRGBA(image.Rect(0, 0, outWidth, outHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var outX, outY int
+			switch orientation {
+			case 2:
+				outX, outY = width-1-x, y
+			case 3:
+				outX, outY = width-1-x, height-1-y
+			case 4:
+				outX, outY = x, height-1-y
+			case 5:
+				outX, outY = y, x
+			case 6:
+				outX, outY = height-1-y, x
+			case 7:
+				outX, outY = height-1-y, width-1-x
+			case 8:
+				outX, outY = y, width-1-x
+			}
+			out.Set(outX, outY, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return out
+}
+
+// thumbnail scales img down to fit in ThumbnailSize x ThumbnailSize while
+// keeping its aspect ratio. Smaller images are returned unchanged. Every
+// output pixel averages a small grid of samples from its source area.
+func thumbnail(img image.Image) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= ThumbnailSize && height <= ThumbnailSize {
+		return img
+	}
+
+	outWidth, outHeight := ThumbnailSize, ThumbnailSize
+	if width > height {
+		outHeight = max(1, height*ThumbnailSize/width)
+	} else {
+		outWidth = max(1, width*ThumbnailSize/height)
+	}
+	scaleX := float64(width) / float64(outWidth)
+	scaleY := float64(height) / float64(outHeight)
+	samplesX := min(maxSamplesPerDim, int(scaleX)+1)
+	samplesY := min(maxSamplesPerDim, int(scaleY)+1)
+
+	out := image.NewRGBA(image.Rect(0, 0, outWidth, outHeight))
+	for y := 0; y < outHeight; y++ {
+		for x := 0; x < outWidth; x++ {
+			var r, g, b, a uint32
+			for sy := 0; sy < samplesY; sy++ {
+				for sx := 0; sx < samplesX; sx++ {
+					srcX := bounds.Min.X + int((float64(x)+(float64(sx)+0.5)/float64(samplesX))*scaleX)
+					srcY := bounds.Min.Y + int((float64(y)+(float64(sy)+0.5)/float64(samplesY))*scaleY)
+					sr, sg, sb, sa := img.At(srcX, srcY).RGBA()
+					r, g, b, a = r+sr, g+sg, b+sb, a+sa
+				}
+			}
+			samples := uint32(samplesX * samplesY)
+			out.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / samples),
+				G: uint16(g / samples),
+				B: uint16(b / samples),
+				A: uint16(a / samples),
+			})
+		}
+	}
+	return out
+}
@@ -0,0 +1,155 @@
+package mfa_test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kwekkwekpatu/chirpy/internal/mfa"
+)
+
+// The SHA-1 secret from RFC 6238, appendix B, base32 encoded.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// The last six digits of the RFC 6238 test vectors.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.want, func(t *testing.T) {
+			got, err := mfa.Code(rfcSecret, mfa.Step(time.Unix(tc.unix, 0)))
+			if err != nil {
+				t.Fatalf("Code failed: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Got code %q at %d, want %q", got, tc.unix, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := mfa.Step(now)
+	current, _ := mfa.Code(rfcSecret, step)
+	previous, _ := mfa.Code(rfcSecret, step-1)
+	stale, _ := mfa.Code(rfcSecret, step-2)
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current code", code: current, wantStep: step, wantOK: true},
+		{name: "previous code", code: previous, wantStep: step - 1, wantOK: true},
+		{name: "padded code", code: " " + current + " ", wantStep: step, wantOK: true},
+		{name: "stale code", code: stale},
+		{name: "wrong code", code: "000000"},
+		{name: "short code", code: "123"},
+		{name: "empty code", code: ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gotStep, gotOK := mfa.Validate(rfcSecret, tc.code, now)
+			if gotOK != tc.wantOK {
+				t.Fatalf("Validate(%q) ok = %v, want %v", tc.code, gotOK, tc.wantOK)
+			}
+			if gotOK && gotStep != tc.wantStep {
+				t.Errorf("Validate(%q) step = %d, want %d", tc.code, gotStep, tc.wantStep)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := mfa.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret failed: %v", err)
+	}
+	code, err := mfa.Code(secret, 1)
+	if err != nil {
+		t.Fatalf("Generated secret %q is not usable: %v", secret, err)
+	}
+	if !mfa.IsCode(code) {
+		t.Errorf("Code %q does not look like a code", code)
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := mfa.URI("Chirpy", "walt@example.com", rfcSecret)
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("URI %q does not parse: %v", uri, err)
+	}
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" {
+		t.Errorf("Got %s://%s, want otpauth://totp", parsed.Scheme, parsed.Host)
+	}
+	if parsed.Path != "/Chirpy:walt@example.com" {
+		t.Errorf("Got label %q", parsed.Path)
+	}
+	query := parsed.Query()
+	if query.Get("secret") != rfcSecret || query.Get("issuer") != "Chirpy" || query.Get("digits") != "6" || query.Get("period") != "30" {
+		t.Errorf("Got unexpected parameters %v", query)
+	}
+}
+
+func TestIsCode(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{input: "123456", want: true},
+		{input: " 123456", want: true},
+		{input: "12345", want: false},
+		{input: "12345a", want: false},
+		{input: "k3m9q-x7r2p", want: false},
+	}
+
+	for _, tc := range tests {
+		if got := mfa.IsCode(tc.input); got != tc.want {
+			t.Errorf("IsCode(%q) = %v, want %v", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := mfa.GenerateRecoveryCodes(mfa.RecoveryCodeCount)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes failed: %v", err)
+	}
+	if len(codes) != mfa.RecoveryCodeCount {
+		t.Fatalf("Got %d codes, want %d", len(codes), mfa.RecoveryCodeCount)
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("Code %q is not formatted as xxxxx-xxxxx", code)
+		}
+		if mfa.IsCode(code) {
+			t.Errorf("Recovery code %q looks like a TOTP code", code)
+		}
+		if seen[code] {
+			t.Errorf("Code %q was generated twice", code)
+		}
+		seen[code] = true
+
+		typed := strings.ToUpper(strings.ReplaceAll(code, "-", " "))
+		if mfa.HashRecoveryCode(typed) != mfa.HashRecoveryCode(code) {
+			t.Errorf("Code %q typed as %q hashes differently", code, typed)
+		}
+	}
+}
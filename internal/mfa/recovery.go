@@ -0,0 +1,56 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	RecoveryCodeCount = 10
+
+	// Recovery codes are two groups of five characters, such as
+	// "k3m9q-x7r2p".
+	recoveryGroupSize = 5
+	recoveryAlphabet  = "abcdefghijkmnpqrstuvwxyz23456789"
+)
+
+// GenerateRecoveryCodes makes n random recovery codes. The alphabet leaves
+// out characters that are easily confused, such as 0 and o.
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		raw := make([]byte, 2*recoveryGroupSize)
+		_, err := rand.Read(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		for j, b := range raw {
+			// 256 is a multiple of the alphabet size, so this is unbiased.
+			raw[j] = recoveryAlphabet[int(b)%len(recoveryAlphabet)]
+		}
+		codes = append(codes, string(raw[:recoveryGroupSize])+"-"+string(raw[recoveryGroupSize:]))
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode undoes the formatting a user might add or drop when
+// typing a code.
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
+
+// HashRecoveryCode is how recovery codes are stored. The codes carry 50 bits
+// of randomness, so a plain hash is enough and lets the database look them up.
+func HashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(NormalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
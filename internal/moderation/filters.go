@@ -0,0 +1,183 @@
+package moderation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	KindWord   = "word"
+	KindRegex  = "regex"
+	KindDomain = "domain"
+)
+
+// Rule is a single moderation rule as it is configured in a rules file or
+// the database.
+type Rule struct {
+	Kind    string
+	Action  Action
+	Pattern string
+}
+
+// NewFilters builds the filter chain for rules: a wordlist, a regex filter
+// and a link domain blocklist, in that order.
+func NewFilters(rules []Rule) ([]Filter, error) {
+	byKind := map[string][]Rule{}
+	for _, rule := range rules {
+		if _, err := ParseAction(string(rule.Action)); err != nil {
+			return nil, err
+		}
+		switch rule.Kind {
+		case KindWord, KindRegex, KindDomain:
+			byKind[rule.Kind] = append(byKind[rule.Kind], rule)
+		default:
+			return nil, fmt.Errorf("Unknown moderation rule kind %q", rule.Kind)
+		}
+	}
+
+	wordlist, err := NewWordlist(byKind[KindWord])
+	if err != nil {
+		return nil, err
+	}
+	regexFilter, err := NewRegexFilter(byKind[KindRegex])
+	if err != nil {
+		return nil, err
+	}
+	domainBlocklist, err := NewDomainBlocklist(byKind[KindDomain])
+	if err != nil {
+		return nil, err
+	}
+	return []Filter{wordlist, regexFilter, domainBlocklist}, nil
+}
+
+// Wordlist matches whole words after normalization, so punctuation around a
+// word, letter case, accents and invisible characters do not hide it.
+type Wordlist struct {
+	words map[string]Rule
+}
+
+func NewWordlist(rules []Rule) (*Wordlist, error) {
+	words := map[string]Rule{}
+	for _, rule := range rules {
+		normalized := Normalize(rule.Pattern)
+		if normalized == "" || strings.IndexFunc(normalized, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) >= 0 {
+			return nil, fmt.Errorf("Word rule %q must be a single word", rule.Pattern)
+		}
+		words[normalized] = rule
+	}
+	return &Wordlist{words: words}, nil
+}
+
+func (w *Wordlist) Check(body string) []Match {
+	matches := []Match{}
+	if len(w.words) == 0 {
+		return matches
+	}
+	for _, found := range words(body) {
+		rule, ok := w.words[found.text]
+		if !ok {
+			continue
+		}
+		matches = append(matches, Match{Filter: KindWord, Rule: rule.Pattern, Action: rule.Action, Start: found.start, End: found.end})
+	}
+	return matches
+}
+
+// RegexFilter matches regular expressions against the body as it was
+// written. Patterns are case sensitive unless they start with (?i).
+type RegexFilter struct {
+	rules    []Rule
+	patterns []*regexp.Regexp
+}
+
+func NewRegexFilter(rules []Rule) (*RegexFilter, error) {
+	filter := &RegexFilter{rules: rules}
+	for _, rule := range rules {
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid regex rule %q: %w", rule.Pattern, err)
+		}
+		filter.patterns = append(filter.patterns, pattern)
+	}
+	return filter, nil
+}
+
+func (f *RegexFilter) Check(body string) []Match {
+	matches := []Match{}
+	for i, pattern := range f.patterns {
+		for _, span := range pattern.FindAllStringIndex(body, -1) {
+			if span[0] == span[1] {
+				continue
+			}
+			matches = append(matches, Match{Filter: KindRegex, Rule: f.rules[i].Pattern, Action: f.rules[i].Action, Start: span[0], End: span[1]})
+		}
+	}
+	return matches
+}
+
+// linkPattern finds anything that looks like a link, with or without a
+// scheme. Only hosts on the blocklist are acted on, so false positives such
+// as file names do no harm.
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://)?(?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,}\b(?::\d+)?(?:[/?#]\S*)?`)
+
+// DomainBlocklist matches links to a blocked domain or any of its
+// subdomains. The whole link is matched, not only the host.
+type DomainBlocklist struct {
+	domains map[string]Rule
+}
+
+func NewDomainBlocklist(rules []Rule) (*DomainBlocklist, error) {
+	domains := map[string]Rule{}
+	for _, rule := range rules {
+		domain := normalizeDomain(rule.Pattern)
+		if !strings.Contains(domain, ".") || strings.ContainsAny(domain, " /:?#") {
+			return nil, fmt.Errorf("Domain rule %q must be a domain name such as example.com", rule.Pattern)
+		}
+		domains[domain] = rule
+	}
+	return &DomainBlocklist{domains: domains}, nil
+}
+
+func normalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	domain = strings.TrimPrefix(domain, "https://")
+	domain = strings.TrimPrefix(domain, "http://")
+	return strings.Trim(domain, ".")
+}
+
+func (b *DomainBlocklist) Check(body string) []Match {
+	matches := []Match{}
+	if len(b.domains) == 0 {
+		return matches
+	}
+	for _, span := range linkPattern.FindAllStringIndex(body, -1) {
+		host := normalizeDomain(body[span[0]:span[1]])
+		if end := strings.IndexAny(host, ":/?#"); end >= 0 {
+			host = host[:end]
+		}
+		rule, ok := b.lookup(host)
+		if !ok {
+			continue
+		}
+		matches = append(matches, Match{Filter: KindDomain, Rule: rule.Pattern, Action: rule.Action, Start: span[0], End: span[1]})
+	}
+	return matches
+}
+
+// lookup finds the rule for host or the closest parent domain of host.
+func (b *DomainBlocklist) lookup(host string) (Rule, bool) {
+	for {
+		if rule, ok := b.domains[host]; ok {
+			return rule, true
+		}
+		dot := strings.Index(host, ".")
+		if dot < 0 {
+			return Rule{}, false
+		}
+		host = host[dot+1:]
+	}
+}
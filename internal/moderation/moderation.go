@@ -0,0 +1,128 @@
+// Package moderation checks chirp bodies against a chain of filters. Every
+// filter reports the spans of a body its rules match together with what
+// should happen to them: mask the span, flag the chirp for review or reject
+// it outright.
+package moderation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask is what masked spans are replaced with.
+const Mask = "****"
+
+type Action string
+
+const (
+	ActionMask   Action = "mask"
+	ActionFlag   Action = "flag"
+	ActionReject Action = "reject"
+)
+
+func ParseAction(action string) (Action, error) {
+	switch Action(action) {
+	case ActionMask, ActionFlag, ActionReject:
+		return Action(action), nil
+	}
+	return "", fmt.Errorf("Unknown moderation action %q", action)
+}
+
+// Match is a span of a body, in bytes, that a rule matched.
+type Match struct {
+	Filter string
+	Rule   string
+	Action Action
+	Start  int
+	End    int
+}
+
+// Filter is one link in the moderation chain.
+type Filter interface {
+	Check(body string) []Match
+}
+
+// Result is the outcome of moderating a body. Body has all masked spans
+// replaced, flagged spans are left as they are.
+type Result struct {
+	Body    string
+	Matches []Match
+}
+
+func (r Result) Rejected() bool {
+	return r.has(ActionReject)
+}
+
+func (r Result) Flagged() bool {
+	return r.has(ActionFlag)
+}
+
+func (r Result) has(action Action) bool {
+	for _, match := range r.Matches {
+		if match.Action == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Pipeline runs a body through its filters. The filters can be swapped while
+// the pipeline is in use, which is how rules are reloaded without a restart.
+type Pipeline struct {
+	mu      sync.RWMutex
+	filters []Filter
+}
+
+func NewPipeline(filters ...Filter) *Pipeline {
+	return &Pipeline{filters: filters}
+}
+
+func (p *Pipeline) SetFilters(filters ...Filter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.filters = filters
+}
+
+func (p *Pipeline) Moderate(body string) Result {
+	p.mu.RLock()
+	filters := p.filters
+	p.mu.RUnlock()
+
+	matches := []Match{}
+	for _, filter := range filters {
+		matches = append(matches, filter.Check(body)...)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Start < matches[j].Start
+	})
+	return Result{Body: mask(body, matches), Matches: matches}
+}
+
+// mask replaces the masked spans of body, merging spans that overlap or
+// touch so that they turn into a single Mask.
+func mask(body string, matches []Match) string {
+	var builder strings.Builder
+	written := 0
+	for i := 0; i < len(matches); i++ {
+		if matches[i].Action != ActionMask {
+			continue
+		}
+		start, end := matches[i].Start, matches[i].End
+		for i+1 < len(matches) && matches[i+1].Start <= end {
+			i++
+			if matches[i].Action == ActionMask && matches[i].End > end {
+				end = matches[i].End
+			}
+		}
+		if start < written {
+			start = written
+		}
+		builder.WriteString(body[written:start])
+		builder.WriteString(Mask)
+		written = end
+	}
+	builder.WriteString(body[written:])
+	return builder.String()
+}
@@ -0,0 +1,243 @@
+package moderation_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kwekkwekpatu/chirpy/internal/moderation"
+)
+
+func newPipeline(t *testing.T, rules []moderation.Rule) *moderation.Pipeline {
+	t.Helper()
+	filters, err := moderation.NewFilters(rules)
+	if err != nil {
+		t.Fatalf("NewFilters() error = %v", err)
+	}
+	return moderation.NewPipeline(filters...)
+}
+
+func TestModerateMasksWords(t *testing.T) {
+	pipeline := newPipeline(t, moderation.DefaultRules())
+
+	testCases := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "clean body",
+			body:     "I had something interesting for breakfast",
+			expected: "I had something interesting for breakfast",
+		},
+		{
+			name:     "lower case",
+			body:     "what a kerfuffle today",
+			expected: "what a **** today",
+		},
+		{
+			name:     "trailing punctuation",
+			body:     "Kerfuffle! and sharbert.",
+			expected: "****! and ****.",
+		},
+		{
+			name:     "not split on single spaces",
+			body:     "fornax\tand\nsharbert,kerfuffle",
+			expected: "****\tand\n****,****",
+		},
+		{
+			name:     "accents and fullwidth letters",
+			body:     "Kérfüffle and ｓｈａｒｂｅｒｔ",
+			expected: "**** and ****",
+		},
+		{
+			name:     "zero width characters",
+			body:     "ker​fuffle​!",
+			expected: "****​!",
+		},
+		{
+			name:     "part of a longer word",
+			body:     "kerfuffles are fine",
+			expected: "kerfuffles are fine",
+		},
+		{
+			name:     "empty body",
+			body:     "",
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := pipeline.Moderate(tc.body)
+			if result.Body != tc.expected {
+				t.Errorf("Moderate(%q).Body = %q, want %q", tc.body, result.Body, tc.expected)
+			}
+			if result.Rejected() || result.Flagged() {
+				t.Errorf("Moderate(%q) rejected or flagged a masked body", tc.body)
+			}
+		})
+	}
+}
+
+func TestModerateActions(t *testing.T) {
+	pipeline := newPipeline(t, []moderation.Rule{
+		{Kind: moderation.KindWord, Action: moderation.ActionMask, Pattern: "kerfuffle"},
+		{Kind: moderation.KindWord, Action: moderation.ActionFlag, Pattern: "scam"},
+		{Kind: moderation.KindRegex, Action: moderation.ActionReject, Pattern: `(?i)buy\s+followers`},
+		{Kind: moderation.KindRegex, Action: moderation.ActionMask, Pattern: `\d{3}-\d{4}`},
+		{Kind: moderation.KindDomain, Action: moderation.ActionMask, Pattern: "spam.example"},
+		{Kind: moderation.KindDomain, Action: moderation.ActionReject, Pattern: "malware.example"},
+	})
+
+	testCases := []struct {
+		name     string
+		body     string
+		expected string
+		rejected bool
+		flagged  bool
+	}{
+		{
+			name:     "regex mask",
+			body:     "call 555-1234 now",
+			expected: "call **** now",
+		},
+		{
+			name:     "regex reject",
+			body:     "Buy   followers here",
+			expected: "Buy   followers here",
+			rejected: true,
+		},
+		{
+			name:     "flag keeps the body",
+			body:     "this is a scam",
+			expected: "this is a scam",
+			flagged:  true,
+		},
+		{
+			name:     "link with scheme and path",
+			body:     "see https://spam.example/offer?id=1 today",
+			expected: "see **** today",
+		},
+		{
+			name:     "subdomain without scheme",
+			body:     "visit www.Spam.Example.",
+			expected: "visit ****.",
+		},
+		{
+			name:     "similar domain is allowed",
+			body:     "visit notspam.example and spam.example.org",
+			expected: "visit notspam.example and spam.example.org",
+		},
+		{
+			name:     "blocked domain rejects",
+			body:     "get it at http://cdn.malware.example",
+			expected: "get it at http://cdn.malware.example",
+			rejected: true,
+		},
+		{
+			name:     "overlapping masks merge",
+			body:     "kerfuffle 555-1234",
+			expected: "**** ****",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := pipeline.Moderate(tc.body)
+			if result.Body != tc.expected {
+				t.Errorf("Moderate(%q).Body = %q, want %q", tc.body, result.Body, tc.expected)
+			}
+			if result.Rejected() != tc.rejected {
+				t.Errorf("Moderate(%q).Rejected() = %v, want %v", tc.body, result.Rejected(), tc.rejected)
+			}
+			if result.Flagged() != tc.flagged {
+				t.Errorf("Moderate(%q).Flagged() = %v, want %v", tc.body, result.Flagged(), tc.flagged)
+			}
+		})
+	}
+}
+
+func TestPipelineSetFilters(t *testing.T) {
+	pipeline := newPipeline(t, moderation.DefaultRules())
+	if got := pipeline.Moderate("fornax").Body; got != moderation.Mask {
+		t.Fatalf("Moderate() = %q before reload, want %q", got, moderation.Mask)
+	}
+
+	filters, err := moderation.NewFilters([]moderation.Rule{
+		{Kind: moderation.KindWord, Action: moderation.ActionMask, Pattern: "chirp"},
+	})
+	if err != nil {
+		t.Fatalf("NewFilters() error = %v", err)
+	}
+	pipeline.SetFilters(filters...)
+
+	if got := pipeline.Moderate("fornax chirp").Body; got != "fornax ****" {
+		t.Errorf("Moderate() = %q after reload, want %q", got, "fornax ****")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{input: "Kerfuffle", expected: "kerfuffle"},
+		{input: "ÇAFÉ", expected: "cafe"},
+		{input: "ｆｏｒｎａｘ！", expected: "fornax!"},
+		{input: "sha­r‍bert", expected: "sharbert"},
+		{input: "café", expected: "cafe"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			if got := moderation.Normalize(tc.input); got != tc.expected {
+				t.Errorf("Normalize(%q) = %q, want %q", tc.input, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []moderation.Rule
+		wantErr  bool
+	}{
+		{
+			name:  "rules with comments",
+			input: "# words\nword mask kerfuffle\n\nregex reject (?i)buy now\ndomain flag spam.example\n",
+			expected: []moderation.Rule{
+				{Kind: moderation.KindWord, Action: moderation.ActionMask, Pattern: "kerfuffle"},
+				{Kind: moderation.KindRegex, Action: moderation.ActionReject, Pattern: "(?i)buy now"},
+				{Kind: moderation.KindDomain, Action: moderation.ActionFlag, Pattern: "spam.example"},
+			},
+		},
+		{name: "missing pattern", input: "word mask", wantErr: true},
+		{name: "unknown action", input: "word delete kerfuffle", wantErr: true},
+		{name: "unknown kind", input: "phrase mask kerfuffle", wantErr: true},
+		{name: "invalid regex", input: "regex mask (unclosed", wantErr: true},
+		{name: "word with spaces", input: "word mask two words", wantErr: true},
+		{name: "domain with path", input: "domain mask spam.example/path", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rules, err := moderation.ParseRules(strings.NewReader(tc.input))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseRules() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if len(rules) != len(tc.expected) {
+				t.Fatalf("ParseRules() = %v, want %v", rules, tc.expected)
+			}
+			for i := range rules {
+				if rules[i] != tc.expected[i] {
+					t.Errorf("ParseRules()[%d] = %v, want %v", i, rules[i], tc.expected[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package moderation
+
+import (
+	"strings"
+	"unicode"
+)
+
+// diacritics maps accented latin letters to their base letter. The first
+// rune of every entry is the base.
+var diacritics = buildDiacritics(
+	"aàáâãäåāăą", "cçćĉċč", "dďđ", "eèéêëēĕėęě", "gĝğġģ", "hĥħ", "iìíîïĩīĭįı",
+	"jĵ", "kķ", "lĺļľŀł", "nñńņňŉ", "oòóôõöøōŏő", "rŕŗř", "sśŝşš", "tţťŧ",
+	"uùúûüũūŭůűų", "wŵ", "yýÿŷ", "zźżž",
+)
+
+func buildDiacritics(entries ...string) map[rune]rune {
+	table := map[rune]rune{}
+	for _, entry := range entries {
+		runes := []rune(entry)
+		for _, r := range runes[1:] {
+			table[r] = runes[0]
+		}
+	}
+	return table
+}
+
+// foldRune maps r to the rune it is compared as: lower case, without
+// diacritics and with fullwidth forms turned into ASCII. Runes that are
+// invisible in a word, such as zero width spaces, soft hyphens and combining
+// marks, fold to nothing and ok is false.
+func foldRune(r rune) (folded rune, ok bool) {
+	if unicode.Is(unicode.Cf, r) || unicode.Is(unicode.Mn, r) {
+		return 0, false
+	}
+	if r >= '！' && r <= '～' {
+		r -= '！' - '!'
+	}
+	r = unicode.ToLower(r)
+	if base, found := diacritics[r]; found {
+		r = base
+	}
+	return r, true
+}
+
+// Normalize folds every rune of s the way rules and bodies are compared, so
+// that "Kérfüffle" and "kerfuffle" are the same word.
+func Normalize(s string) string {
+	var builder strings.Builder
+	for _, r := range s {
+		if folded, ok := foldRune(r); ok {
+			builder.WriteRune(folded)
+		}
+	}
+	return builder.String()
+}
+
+type word struct {
+	text  string
+	start int
+	end   int
+}
+
+// words splits body into normalized words made of letters and digits and
+// remembers where in body each word came from. Punctuation separates words,
+// runes that fold to nothing do not.
+func words(body string) []word {
+	found := []word{}
+	var current strings.Builder
+	start := -1
+	for i, r := range body {
+		folded, ok := foldRune(r)
+		if !ok {
+			continue
+		}
+		if unicode.IsLetter(folded) || unicode.IsDigit(folded) {
+			if start < 0 {
+				start = i
+			}
+			current.WriteRune(folded)
+			continue
+		}
+		if start >= 0 {
+			found = append(found, word{text: current.String(), start: start, end: i})
+			current.Reset()
+			start = -1
+		}
+	}
+	if start >= 0 {
+		found = append(found, word{text: current.String(), start: start, end: len(body)})
+	}
+	return trimInvisible(body, found)
+}
+
+// trimInvisible moves the end of each word back over runes that fold to
+// nothing, so masking a word keeps whatever followed it intact.
+func trimInvisible(body string, found []word) []word {
+	for i := range found {
+		text := body[found[i].start:found[i].end]
+		found[i].end = found[i].start + len(strings.TrimRightFunc(text, func(r rune) bool {
+			_, ok := foldRune(r)
+			return !ok
+		}))
+	}
+	return found
+}
@@ -0,0 +1,60 @@
+package moderation
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DefaultRules are used when no rules file is configured.
+func DefaultRules() []Rule {
+	return []Rule{
+		{Kind: KindWord, Action: ActionMask, Pattern: "kerfuffle"},
+		{Kind: KindWord, Action: ActionMask, Pattern: "sharbert"},
+		{Kind: KindWord, Action: ActionMask, Pattern: "fornax"},
+	}
+}
+
+// ParseRules reads one rule per line in the form "<kind> <action> <pattern>",
+// for example "word mask kerfuffle" or "regex reject (?i)buy now". The
+// pattern is the rest of the line and may contain spaces. Blank lines and
+// lines starting with # are ignored.
+func ParseRules(reader io.Reader) ([]Rule, error) {
+	rules := []Rule{}
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(text, " ", 3)
+		if len(fields) != 3 || strings.TrimSpace(fields[2]) == "" {
+			return nil, fmt.Errorf("Line %d: expected \"<kind> <action> <pattern>\"", line)
+		}
+		action, err := ParseAction(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: %w", line, err)
+		}
+		rules = append(rules, Rule{Kind: fields[0], Action: action, Pattern: strings.TrimSpace(fields[2])})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := NewFilters(rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func LoadRules(path string) ([]Rule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseRules(file)
+}
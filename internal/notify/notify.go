@@ -0,0 +1,108 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/broadcast"
+	"github.com/kwekkwekpatu/chirpy/internal/database"
+)
+
+const (
+	TypeFollow   = "follow"
+	TypeLike     = "like"
+	TypeReply    = "reply"
+	TypeMention  = "mention"
+	TypeUpgraded = "upgraded"
+)
+
+// Event is a domain event that a user should be told about. Actor and ChirpID
+// are optional, e.g. an upgrade through the Polka webhook has no actor.
+type Event struct {
+	Type      string
+	Recipient uuid.UUID
+	Actor     uuid.NullUUID
+	ChirpID   uuid.NullUUID
+}
+
+// isSelf reports whether the recipient caused the event. Nobody needs to hear
+// about their own actions.
+func (e Event) isSelf() bool {
+	return e.Actor.Valid && e.Actor.UUID == e.Recipient
+}
+
+// Notifier is implemented by everything that delivers events to users.
+// Producers only depend on this interface.
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// Store persists events as rows in the notifications table.
+type Store struct {
+	db *database.Queries
+}
+
+func NewStore(db *database.Queries) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) Notify(ctx context.Context, event Event) error {
+	if event.isSelf() {
+		return nil
+	}
+
+	return s.db.CreateNotification(ctx, database.CreateNotificationParams{
+		UserID:  event.Recipient,
+		ActorID: event.Actor,
+		Type:    event.Type,
+		ChirpID: event.ChirpID,
+	})
+}
+
+// Publisher pushes events to connected clients through a broadcaster, keyed
+// by recipient.
+type Publisher struct {
+	events *broadcast.Broadcaster
+}
+
+func NewPublisher(events *broadcast.Broadcaster) *Publisher {
+	return &Publisher{events: events}
+}
+
+func (p *Publisher) Notify(ctx context.Context, event Event) error {
+	if event.isSelf() {
+		return nil
+	}
+
+	data, err := json.Marshal(struct {
+		Type    string        `json:"type"`
+		ActorID uuid.NullUUID `json:"actor_id"`
+		ChirpID uuid.NullUUID `json:"chirp_id"`
+	}{
+		Type:    event.Type,
+		ActorID: event.Actor,
+		ChirpID: event.ChirpID,
+	})
+	if err != nil {
+		return err
+	}
+	p.events.Publish("notification."+event.Type, event.Recipient, data)
+	return nil
+}
+
+// Multi delivers every event to all of its notifiers, even when one of them
+// fails.
+type Multi []Notifier
+
+func (m Multi) Notify(ctx context.Context, event Event) error {
+	errs := []error{}
+	for _, notifier := range m {
+		err := notifier.Notify(ctx, event)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
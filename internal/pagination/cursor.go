@@ -0,0 +1,118 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 100
+)
+
+// Cursor points at the last row of a page. Rows are ordered by
+// (created_at, id) so the id breaks ties between equal timestamps.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func EncodeCursor(cursor Cursor) string {
+	raw := cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(encoded string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("Invalid cursor encoding")
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return Cursor{}, fmt.Errorf("Invalid cursor format")
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return Cursor{}, fmt.Errorf("Invalid cursor timestamp")
+	}
+
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return Cursor{}, fmt.Errorf("Invalid cursor id")
+	}
+
+	return Cursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+// Params holds the parsed limit and optional cursor of a paginated request.
+type Params struct {
+	Limit  int
+	Cursor *Cursor
+}
+
+func ParseParams(query url.Values) (Params, error) {
+	limit, err := ParseLimit(query)
+	if err != nil {
+		return Params{}, err
+	}
+	params := Params{Limit: limit}
+
+	cursorString := query.Get("cursor")
+	if cursorString != "" {
+		cursor, err := DecodeCursor(cursorString)
+		if err != nil {
+			return Params{}, err
+		}
+		params.Cursor = &cursor
+	}
+
+	return params, nil
+}
+
+func ParseLimit(query url.Values) (int, error) {
+	limitString := query.Get("limit")
+	if limitString == "" {
+		return DefaultLimit, nil
+	}
+
+	limit, err := strconv.Atoi(limitString)
+	if err != nil || limit < 1 {
+		return 0, fmt.Errorf("limit must be a positive integer")
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	return limit, nil
+}
+
+// EncodeOffset builds an opaque cursor for result sets that are not ordered
+// by (created_at, id), such as ranked search results.
+func EncodeOffset(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset|" + strconv.Itoa(offset)))
+}
+
+func DecodeOffset(encoded string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid cursor encoding")
+	}
+
+	offsetString, found := strings.CutPrefix(string(raw), "offset|")
+	if !found {
+		return 0, fmt.Errorf("Invalid cursor format")
+	}
+
+	offset, err := strconv.Atoi(offsetString)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("Invalid cursor offset")
+	}
+	return offset, nil
+}
@@ -0,0 +1,115 @@
+package pagination_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kwekkwekpatu/chirpy/internal/pagination"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := pagination.Cursor{
+		CreatedAt: time.Date(2024, 11, 3, 10, 15, 30, 123456000, time.UTC),
+		ID:        uuid.New(),
+	}
+
+	decoded, err := pagination.DecodeCursor(pagination.EncodeCursor(cursor))
+	if err != nil {
+		t.Fatalf("DecodeCursor failed with valid cursor: %v", err)
+	}
+	if !decoded.CreatedAt.Equal(cursor.CreatedAt) {
+		t.Errorf("Got wrong created_at. Want %v, got %v", cursor.CreatedAt, decoded.CreatedAt)
+	}
+	if decoded.ID != cursor.ID {
+		t.Errorf("Got wrong id. Want %v, got %v", cursor.ID, decoded.ID)
+	}
+}
+
+func TestOffsetRoundTrip(t *testing.T) {
+	offset, err := pagination.DecodeOffset(pagination.EncodeOffset(150))
+	if err != nil {
+		t.Fatalf("DecodeOffset failed with valid cursor: %v", err)
+	}
+	if offset != 150 {
+		t.Errorf("Got wrong offset. Want 150, got %d", offset)
+	}
+
+	keysetCursor := pagination.EncodeCursor(pagination.Cursor{CreatedAt: time.Now(), ID: uuid.New()})
+	_, err = pagination.DecodeOffset(keysetCursor)
+	if err == nil {
+		t.Error("DecodeOffset accepted a keyset cursor")
+	}
+}
+
+func TestParseParams(t *testing.T) {
+	validCursor := pagination.EncodeCursor(pagination.Cursor{CreatedAt: time.Now(), ID: uuid.New()})
+
+	testCases := []struct {
+		name          string
+		query         url.Values
+		expectedLimit int
+		expectCursor  bool
+		expectedError bool
+	}{
+		{
+			name:          "defaults",
+			query:         url.Values{},
+			expectedLimit: pagination.DefaultLimit,
+		},
+		{
+			name:          "explicit limit",
+			query:         url.Values{"limit": []string{"10"}},
+			expectedLimit: 10,
+		},
+		{
+			name:          "limit is capped",
+			query:         url.Values{"limit": []string{"1000"}},
+			expectedLimit: pagination.MaxLimit,
+		},
+		{
+			name:          "negative limit",
+			query:         url.Values{"limit": []string{"-1"}},
+			expectedError: true,
+		},
+		{
+			name:          "non numeric limit",
+			query:         url.Values{"limit": []string{"ten"}},
+			expectedError: true,
+		},
+		{
+			name:          "valid cursor",
+			query:         url.Values{"cursor": []string{validCursor}},
+			expectedLimit: pagination.DefaultLimit,
+			expectCursor:  true,
+		},
+		{
+			name:          "malformed cursor",
+			query:         url.Values{"cursor": []string{"not-a-cursor"}},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			params, err := pagination.ParseParams(tc.query)
+
+			if tc.expectedError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if params.Limit != tc.expectedLimit {
+				t.Errorf("expected limit %d, got %d", tc.expectedLimit, params.Limit)
+			}
+			if (params.Cursor != nil) != tc.expectCursor {
+				t.Errorf("expected cursor present to be %v", tc.expectCursor)
+			}
+		})
+	}
+}
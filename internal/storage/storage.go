@@ -0,0 +1,41 @@
+// Package storage keeps binary objects such as uploaded media outside of the
+// database.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Backend stores objects under slash separated keys. Keys must pass ValidKey.
+type Backend interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// ValidKey reports whether key is safe to use as both a file path and an
+// object name: relative, no empty or dot segments and only unreserved
+// characters.
+func ValidKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+		for _, r := range segment {
+			isUnreserved := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') ||
+				r == '-' || r == '_' || r == '.' || r == '~'
+			if !isUnreserved {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,235 @@
+package storage_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kwekkwekpatu/chirpy/internal/storage"
+)
+
+const (
+	testAccessKey = "minioadmin"
+	testSecretKey = "minioadmin-secret"
+	testRegion    = "us-east-1"
+	testBucket    = "chirpy"
+)
+
+func TestValidKey(t *testing.T) {
+	testCases := []struct {
+		key   string
+		valid bool
+	}{
+		{key: "media/abc/original.jpg", valid: true},
+		{key: "thumbnail.png", valid: true},
+		{key: "", valid: false},
+		{key: "/absolute", valid: false},
+		{key: "media/../secret", valid: false},
+		{key: "media//double", valid: false},
+		{key: "media/with space", valid: false},
+		{key: "media/query?x=1", valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			if got := storage.ValidKey(tc.key); got != tc.valid {
+				t.Errorf("ValidKey(%q) = %v, want %v", tc.key, got, tc.valid)
+			}
+		})
+	}
+}
+
+func TestLocal(t *testing.T) {
+	backend, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal failed: %v", err)
+	}
+	testBackend(t, backend)
+}
+
+func TestS3(t *testing.T) {
+	server := httptest.NewServer(newFakeS3(t))
+	defer server.Close()
+
+	backend, err := storage.NewS3(storage.S3Config{
+		Endpoint:  server.URL,
+		Bucket:    testBucket,
+		Region:    testRegion,
+		AccessKey: testAccessKey,
+		SecretKey: testSecretKey,
+	}, server.Client())
+	if err != nil {
+		t.Fatalf("NewS3 failed: %v", err)
+	}
+	testBackend(t, backend)
+}
+
+func TestS3RejectsWrongCredentials(t *testing.T) {
+	server := httptest.NewServer(newFakeS3(t))
+	defer server.Close()
+
+	backend, err := storage.NewS3(storage.S3Config{
+		Endpoint:  server.URL,
+		Bucket:    testBucket,
+		AccessKey: testAccessKey,
+		SecretKey: "wrong",
+	}, server.Client())
+	if err != nil {
+		t.Fatalf("NewS3 failed: %v", err)
+	}
+
+	err = backend.Put(context.Background(), "media/a.txt", []byte("data"), "text/plain")
+	if err == nil {
+		t.Error("expected error but got none")
+	}
+}
+
+func testBackend(t *testing.T, backend storage.Backend) {
+	ctx := context.Background()
+
+	_, err := backend.Get(ctx, "media/missing.png")
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for missing object, got %v", err)
+	}
+
+	err = backend.Put(ctx, "media/abc/original.png", []byte("first"), "image/png")
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	err = backend.Put(ctx, "media/abc/original.png", []byte("second"), "image/png")
+	if err != nil {
+		t.Fatalf("Put overwrite failed: %v", err)
+	}
+
+	reader, err := backend.Get(ctx, "media/abc/original.png")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		t.Fatalf("Reading object failed: %v", err)
+	}
+	if string(data) != "second" {
+		t.Errorf("Got wrong object data. Want 'second', got '%s'", data)
+	}
+
+	err = backend.Put(ctx, "../escape", []byte("data"), "text/plain")
+	if err == nil {
+		t.Error("Put accepted an invalid key")
+	}
+
+	err = backend.Delete(ctx, "media/abc/original.png")
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	err = backend.Delete(ctx, "media/abc/original.png")
+	if err != nil {
+		t.Errorf("Deleting a missing object should succeed, got %v", err)
+	}
+	_, err = backend.Get(ctx, "media/abc/original.png")
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+// fakeS3 is an in-memory stand-in for an S3 compatible server such as MinIO.
+// It verifies request signatures the way the real server does.
+type fakeS3 struct {
+	t       *testing.T
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newFakeS3(t *testing.T) *fakeS3 {
+	return &fakeS3{t: t, objects: map[string][]byte{}}
+}
+
+func (f *fakeS3) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !f.validSignature(request, body) {
+		writer.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	key, found := strings.CutPrefix(request.URL.Path, "/"+testBucket+"/")
+	if !found {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch request.Method {
+	case http.MethodPut:
+		f.objects[key] = body
+		writer.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writer.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		writer.WriteHeader(http.StatusNoContent)
+	default:
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeS3) validSignature(request *http.Request, body []byte) bool {
+	payloadHash := sha256.Sum256(body)
+	if request.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(payloadHash[:]) {
+		f.t.Log("payload hash mismatch")
+		return false
+	}
+
+	amzDate := request.Header.Get("X-Amz-Date")
+	if len(amzDate) != len("20060102T150405Z") {
+		f.t.Log("missing x-amz-date")
+		return false
+	}
+	date := amzDate[:8]
+	scope := date + "/" + testRegion + "/s3/aws4_request"
+
+	canonicalRequest := request.Method + "\n" +
+		request.URL.Path + "\n" +
+		"\n" +
+		"host:" + request.Host + "\n" +
+		"x-amz-content-sha256:" + request.Header.Get("X-Amz-Content-Sha256") + "\n" +
+		"x-amz-date:" + amzDate + "\n" +
+		"\n" +
+		"host;x-amz-content-sha256;x-amz-date\n" +
+		request.Header.Get("X-Amz-Content-Sha256")
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := []byte("AWS4" + testSecretKey)
+	for _, part := range []string{date, testRegion, "s3", "aws4_request"} {
+		key = sign(key, part)
+	}
+	expected := "AWS4-HMAC-SHA256 Credential=" + testAccessKey + "/" + scope +
+		", SignedHeaders=host;x-amz-content-sha256;x-amz-date" +
+		", Signature=" + hex.EncodeToString(sign(key, stringToSign))
+	return request.Header.Get("Authorization") == expected
+}
+
+func sign(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
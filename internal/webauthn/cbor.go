@@ -0,0 +1,155 @@
+package webauthn
+
+import (
+	"fmt"
+	"math"
+)
+
+// maxDepth bounds nesting so hostile input cannot exhaust the stack.
+const maxDepth = 16
+
+// decodeCBOR decodes the CBOR item (RFC 8949) at the start of data and
+// returns it with the number of bytes it took. It only supports what
+// authenticators send: integers, byte and text strings, arrays, maps, tags
+// and the simple values, all with definite lengths. Integers decode to
+// int64, maps to map[interface{}]interface{} with int64 or string keys.
+func decodeCBOR(data []byte) (interface{}, int, error) {
+	d := decoder{data: data}
+	value, err := d.value(0)
+	if err != nil {
+		return nil, 0, err
+	}
+	return value, d.pos, nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) remaining() uint64 {
+	return uint64(len(d.data) - d.pos)
+}
+
+// head reads the initial byte of an item and its argument.
+func (d *decoder) head() (byte, uint64, error) {
+	if d.remaining() < 1 {
+		return 0, 0, fmt.Errorf("cbor: unexpected end of data")
+	}
+	initial := d.data[d.pos]
+	d.pos++
+	major, info := initial>>5, initial&0x1f
+
+	var size int
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	case info == 31:
+		return 0, 0, fmt.Errorf("cbor: indefinite lengths are not supported")
+	default:
+		return 0, 0, fmt.Errorf("cbor: reserved additional information %d", info)
+	}
+
+	if d.remaining() < uint64(size) {
+		return 0, 0, fmt.Errorf("cbor: unexpected end of data")
+	}
+	var arg uint64
+	for _, b := range d.data[d.pos : d.pos+size] {
+		arg = arg<<8 | uint64(b)
+	}
+	d.pos += size
+	return major, arg, nil
+}
+
+func (d *decoder) value(depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("cbor: nested too deeply")
+	}
+
+	major, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case 0:
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("cbor: integer overflows int64")
+		}
+		return int64(arg), nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("cbor: integer overflows int64")
+		}
+		return -1 - int64(arg), nil
+	case 2, 3:
+		if d.remaining() < arg {
+			return nil, fmt.Errorf("cbor: unexpected end of data")
+		}
+		raw := d.data[d.pos : d.pos+int(arg)]
+		d.pos += int(arg)
+		if major == 3 {
+			return string(raw), nil
+		}
+		return append([]byte(nil), raw...), nil
+	case 4:
+		// Every item takes at least a byte, which bounds the allocation.
+		if d.remaining() < arg {
+			return nil, fmt.Errorf("cbor: unexpected end of data")
+		}
+		items := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			item, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case 5:
+		if d.remaining() < 2*arg {
+			return nil, fmt.Errorf("cbor: unexpected end of data")
+		}
+		entries := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			key, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, fmt.Errorf("cbor: unsupported map key type %T", key)
+			}
+			if _, ok := entries[key]; ok {
+				return nil, fmt.Errorf("cbor: duplicate map key %v", key)
+			}
+			entries[key], err = d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return entries, nil
+	case 6:
+		// Tags only add meaning to the item that follows.
+		return d.value(depth + 1)
+	default:
+		switch arg {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cbor: unsupported simple value or float %d", arg)
+	}
+}
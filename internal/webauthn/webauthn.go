@@ -0,0 +1,497 @@
+// Package webauthn implements the relying party side of WebAuthn (Level 2)
+// for passkey registration and login. It accepts ES256, EdDSA and RS256
+// credentials and does not check attestation: Chirpy asks authenticators for
+// none, because it has no use for knowing the make and model of a device.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	ChallengeSize = 32
+
+	// CeremonyTimeout is how long the browser and the server wait for the
+	// user to finish with their authenticator.
+	CeremonyTimeout = 5 * time.Minute
+
+	UserVerificationRequired  = "required"
+	UserVerificationPreferred = "preferred"
+)
+
+// Authenticator data flags.
+const (
+	flagUserPresent    = 0x01
+	flagUserVerified   = 0x04
+	flagBackupEligible = 0x08
+	flagBackedUp       = 0x10
+	flagAttested       = 0x40
+	flagExtensions     = 0x80
+)
+
+var (
+	ErrChallenge = errors.New("webauthn: challenge does not match")
+	ErrOrigin    = errors.New("webauthn: origin is not allowed")
+	ErrRPID      = errors.New("webauthn: credential is for another relying party")
+	ErrUser      = errors.New("webauthn: user was not present or verified")
+	// ErrSignCount means the authenticator's counter went backwards, so the
+	// credential may have been cloned.
+	ErrSignCount = errors.New("webauthn: signature counter did not increase")
+)
+
+// Binary fields are base64url without padding, as in the JSON form of
+// PublicKeyCredential that browsers produce.
+var encoding = base64.RawURLEncoding
+
+// RelyingParty is the site credentials are registered with. ID is its
+// domain, Origins are the exact origins, such as "https://chirpy.example",
+// that ceremonies may run on.
+type RelyingParty struct {
+	ID               string
+	Name             string
+	Origins          []string
+	UserVerification string
+}
+
+func NewRelyingParty(id, name string, origins []string) *RelyingParty {
+	return &RelyingParty{ID: id, Name: name, Origins: origins, UserVerification: UserVerificationRequired}
+}
+
+// NewChallenge makes a random challenge for a ceremony.
+func NewChallenge() (string, error) {
+	challenge := make([]byte, ChallengeSize)
+	_, err := rand.Read(challenge)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	return encoding.EncodeToString(challenge), nil
+}
+
+// EncodeID and DecodeID convert credential IDs and user handles to and from
+// their JSON form.
+func EncodeID(id []byte) string {
+	return encoding.EncodeToString(id)
+}
+
+func DecodeID(id string) ([]byte, error) {
+	return encoding.DecodeString(id)
+}
+
+type RPEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type      string `json:"type"`
+	Algorithm int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+// CreationOptions are passed to navigator.credentials.create.
+type CreationOptions struct {
+	PublicKey PublicKeyCredentialCreationOptions `json:"publicKey"`
+}
+
+type PublicKeyCredentialCreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     RPEntity               `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are passed to navigator.credentials.get.
+type RequestOptions struct {
+	PublicKey PublicKeyCredentialRequestOptions `json:"publicKey"`
+}
+
+type PublicKeyCredentialRequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// CreationOptions asks for a discoverable credential, so the user can later
+// log in without typing their email. existing lists the user's credentials,
+// which an authenticator must not register twice.
+func (rp *RelyingParty) CreationOptions(challenge string, user UserEntity, existing []CredentialDescriptor) CreationOptions {
+	params := []CredentialParameter{}
+	for _, algorithm := range supportedAlgorithms {
+		params = append(params, CredentialParameter{Type: "public-key", Algorithm: algorithm})
+	}
+	if existing == nil {
+		existing = []CredentialDescriptor{}
+	}
+
+	return CreationOptions{PublicKey: PublicKeyCredentialCreationOptions{
+		Challenge:          challenge,
+		RP:                 RPEntity{ID: rp.ID, Name: rp.Name},
+		User:               user,
+		PubKeyCredParams:   params,
+		Timeout:            CeremonyTimeout.Milliseconds(),
+		ExcludeCredentials: existing,
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:        "required",
+			RequireResidentKey: true,
+			UserVerification:   rp.UserVerification,
+		},
+		Attestation: "none",
+	}}
+}
+
+// RequestOptions leaves allowCredentials empty, so the authenticator offers
+// whichever of its passkeys belong to this site.
+func (rp *RelyingParty) RequestOptions(challenge string) RequestOptions {
+	return RequestOptions{PublicKey: PublicKeyCredentialRequestOptions{
+		Challenge:        challenge,
+		Timeout:          CeremonyTimeout.Milliseconds(),
+		RPID:             rp.ID,
+		AllowCredentials: []CredentialDescriptor{},
+		UserVerification: rp.UserVerification,
+	}}
+}
+
+// RegistrationResponse is the JSON form of the PublicKeyCredential returned
+// by navigator.credentials.create.
+type RegistrationResponse struct {
+	ID       string                           `json:"id"`
+	RawID    string                           `json:"rawId"`
+	Type     string                           `json:"type"`
+	Response AuthenticatorAttestationResponse `json:"response"`
+}
+
+type AuthenticatorAttestationResponse struct {
+	ClientDataJSON    string   `json:"clientDataJSON"`
+	AttestationObject string   `json:"attestationObject"`
+	Transports        []string `json:"transports,omitempty"`
+}
+
+// AssertionResponse is the JSON form of the PublicKeyCredential returned by
+// navigator.credentials.get.
+type AssertionResponse struct {
+	ID       string                         `json:"id"`
+	RawID    string                         `json:"rawId"`
+	Type     string                         `json:"type"`
+	Response AuthenticatorAssertionResponse `json:"response"`
+}
+
+type AuthenticatorAssertionResponse struct {
+	ClientDataJSON    string `json:"clientDataJSON"`
+	AuthenticatorData string `json:"authenticatorData"`
+	Signature         string `json:"signature"`
+	UserHandle        string `json:"userHandle,omitempty"`
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+func parseClientData(encoded string) (clientData, []byte, error) {
+	raw, err := encoding.DecodeString(encoded)
+	if err != nil {
+		return clientData{}, nil, fmt.Errorf("webauthn: invalid clientDataJSON encoding: %w", err)
+	}
+	data := clientData{}
+	err = json.Unmarshal(raw, &data)
+	if err != nil {
+		return clientData{}, nil, fmt.Errorf("webauthn: invalid clientDataJSON: %w", err)
+	}
+	return data, raw, nil
+}
+
+// Challenge is the challenge the browser signed. It is not verified; use it
+// to find the ceremony the response belongs to.
+func (response RegistrationResponse) Challenge() (string, error) {
+	data, _, err := parseClientData(response.Response.ClientDataJSON)
+	return data.Challenge, err
+}
+
+// Challenge is the challenge the browser signed. It is not verified; use it
+// to find the ceremony the response belongs to.
+func (response AssertionResponse) Challenge() (string, error) {
+	data, _, err := parseClientData(response.Response.ClientDataJSON)
+	return data.Challenge, err
+}
+
+// CredentialID is the decoded raw ID of the credential.
+func (response AssertionResponse) CredentialID() ([]byte, error) {
+	return encoding.DecodeString(response.RawID)
+}
+
+func (rp *RelyingParty) verifyClientData(data clientData, ceremony, challenge string) error {
+	if data.Type != ceremony {
+		return fmt.Errorf("webauthn: unexpected client data type %q", data.Type)
+	}
+	if subtle.ConstantTimeCompare([]byte(data.Challenge), []byte(challenge)) != 1 {
+		return ErrChallenge
+	}
+	if data.CrossOrigin {
+		return ErrOrigin
+	}
+	for _, origin := range rp.Origins {
+		if data.Origin == origin {
+			return nil
+		}
+	}
+	return ErrOrigin
+}
+
+type authenticatorData struct {
+	raw          []byte
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte
+}
+
+func parseAuthenticatorData(raw []byte) (authenticatorData, error) {
+	// The RP ID hash, flags and the signature counter come first.
+	if len(raw) < 37 {
+		return authenticatorData{}, fmt.Errorf("webauthn: authenticator data is too short")
+	}
+	data := authenticatorData{
+		raw:       raw,
+		rpIDHash:  raw[:32],
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	rest := raw[37:]
+
+	if data.flags&flagAttested != 0 {
+		// The AAGUID, the credential ID length, the ID and the COSE key.
+		if len(rest) < 18 {
+			return authenticatorData{}, fmt.Errorf("webauthn: attested credential data is too short")
+		}
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < idLength {
+			return authenticatorData{}, fmt.Errorf("webauthn: credential ID is truncated")
+		}
+		data.credentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return authenticatorData{}, fmt.Errorf("webauthn: invalid credential public key: %w", err)
+		}
+		data.publicKey = rest[:n]
+		rest = rest[n:]
+	}
+
+	if data.flags&flagExtensions != 0 {
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return authenticatorData{}, fmt.Errorf("webauthn: invalid extensions: %w", err)
+		}
+		rest = rest[n:]
+	}
+
+	if len(rest) != 0 {
+		return authenticatorData{}, fmt.Errorf("webauthn: trailing data after authenticator data")
+	}
+	return data, nil
+}
+
+func (rp *RelyingParty) verifyAuthenticatorData(data authenticatorData) error {
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(data.rpIDHash, rpIDHash[:]) {
+		return ErrRPID
+	}
+	if data.flags&flagUserPresent == 0 {
+		return ErrUser
+	}
+	if rp.UserVerification == UserVerificationRequired && data.flags&flagUserVerified == 0 {
+		return ErrUser
+	}
+	return nil
+}
+
+// Credential is what has to be stored about a registered credential.
+// PublicKey is in COSE form.
+type Credential struct {
+	ID             []byte
+	PublicKey      []byte
+	Algorithm      int64
+	SignCount      uint32
+	BackupEligible bool
+	BackedUp       bool
+	Transports     []string
+}
+
+// VerifyRegistration checks the response to CreationOptions made with
+// challenge and returns the new credential.
+func (rp *RelyingParty) VerifyRegistration(challenge string, response RegistrationResponse) (Credential, error) {
+	if response.Type != "public-key" {
+		return Credential{}, fmt.Errorf("webauthn: unexpected credential type %q", response.Type)
+	}
+
+	data, _, err := parseClientData(response.Response.ClientDataJSON)
+	if err != nil {
+		return Credential{}, err
+	}
+	err = rp.verifyClientData(data, "webauthn.create", challenge)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	rawAttestation, err := encoding.DecodeString(response.Response.AttestationObject)
+	if err != nil {
+		return Credential{}, fmt.Errorf("webauthn: invalid attestationObject encoding: %w", err)
+	}
+	value, n, err := decodeCBOR(rawAttestation)
+	if err != nil {
+		return Credential{}, fmt.Errorf("webauthn: invalid attestationObject: %w", err)
+	}
+	attestation, ok := value.(map[interface{}]interface{})
+	if !ok || n != len(rawAttestation) {
+		return Credential{}, fmt.Errorf("webauthn: invalid attestationObject")
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return Credential{}, fmt.Errorf("webauthn: attestationObject has no authData")
+	}
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return Credential{}, err
+	}
+	err = rp.verifyAuthenticatorData(authData)
+	if err != nil {
+		return Credential{}, err
+	}
+	if authData.flags&flagAttested == 0 {
+		return Credential{}, fmt.Errorf("webauthn: authenticator data has no credential")
+	}
+
+	rawID, err := encoding.DecodeString(response.RawID)
+	if err != nil || !bytes.Equal(rawID, authData.credentialID) {
+		return Credential{}, fmt.Errorf("webauthn: credential ID does not match")
+	}
+
+	key, err := parsePublicKey(authData.publicKey)
+	if err != nil {
+		return Credential{}, fmt.Errorf("webauthn: %w", err)
+	}
+
+	return Credential{
+		ID:             authData.credentialID,
+		PublicKey:      authData.publicKey,
+		Algorithm:      key.algorithm,
+		SignCount:      authData.signCount,
+		BackupEligible: authData.flags&flagBackupEligible != 0,
+		BackedUp:       authData.flags&flagBackedUp != 0,
+		Transports:     response.Response.Transports,
+	}, nil
+}
+
+// Assertion is what a verified login tells about the credential.
+type Assertion struct {
+	SignCount    uint32
+	UserVerified bool
+	BackedUp     bool
+}
+
+// VerifyAssertion checks the response to RequestOptions made with challenge
+// against the stored credential, found by the response's CredentialID.
+// userHandle is the user handle the credential was registered with.
+func (rp *RelyingParty) VerifyAssertion(challenge string, credential Credential, userHandle []byte, response AssertionResponse) (Assertion, error) {
+	if response.Type != "public-key" {
+		return Assertion{}, fmt.Errorf("webauthn: unexpected credential type %q", response.Type)
+	}
+
+	rawID, err := response.CredentialID()
+	if err != nil || !bytes.Equal(rawID, credential.ID) {
+		return Assertion{}, fmt.Errorf("webauthn: credential ID does not match")
+	}
+	if response.Response.UserHandle != "" {
+		handle, err := encoding.DecodeString(response.Response.UserHandle)
+		if err != nil || !bytes.Equal(handle, userHandle) {
+			return Assertion{}, fmt.Errorf("webauthn: user handle does not match")
+		}
+	}
+
+	data, rawClientData, err := parseClientData(response.Response.ClientDataJSON)
+	if err != nil {
+		return Assertion{}, err
+	}
+	err = rp.verifyClientData(data, "webauthn.get", challenge)
+	if err != nil {
+		return Assertion{}, err
+	}
+
+	rawAuthData, err := encoding.DecodeString(response.Response.AuthenticatorData)
+	if err != nil {
+		return Assertion{}, fmt.Errorf("webauthn: invalid authenticatorData encoding: %w", err)
+	}
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return Assertion{}, err
+	}
+	err = rp.verifyAuthenticatorData(authData)
+	if err != nil {
+		return Assertion{}, err
+	}
+
+	signature, err := encoding.DecodeString(response.Response.Signature)
+	if err != nil {
+		return Assertion{}, fmt.Errorf("webauthn: invalid signature encoding: %w", err)
+	}
+	key, err := parsePublicKey(credential.PublicKey)
+	if err != nil {
+		return Assertion{}, fmt.Errorf("webauthn: %w", err)
+	}
+	clientDataHash := sha256.Sum256(rawClientData)
+	signed := append(append([]byte{}, authData.raw...), clientDataHash[:]...)
+	err = key.verify(signed, signature)
+	if err != nil {
+		return Assertion{}, fmt.Errorf("webauthn: %w", err)
+	}
+
+	// Synced passkeys always report zero; a counter that is in use must
+	// keep increasing.
+	if (authData.signCount != 0 || credential.SignCount != 0) && authData.signCount <= credential.SignCount {
+		return Assertion{}, ErrSignCount
+	}
+
+	return Assertion{
+		SignCount:    authData.signCount,
+		UserVerified: authData.flags&flagUserVerified != 0,
+		BackedUp:     authData.flags&flagBackedUp != 0,
+	}, nil
+}
@@ -0,0 +1,370 @@
+package webauthn_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/kwekkwekpatu/chirpy/internal/webauthn"
+)
+
+const (
+	rpID   = "chirpy.example"
+	origin = "https://chirpy.example"
+)
+
+var b64 = base64.RawURLEncoding
+
+// encodeCBOR writes the subset of CBOR the tests need, with map keys in
+// canonical order like an authenticator would.
+func encodeCBOR(value interface{}) []byte {
+	head := func(major byte, arg uint64) []byte {
+		switch {
+		case arg < 24:
+			return []byte{major<<5 | byte(arg)}
+		case arg < 1<<8:
+			return []byte{major<<5 | 24, byte(arg)}
+		case arg < 1<<16:
+			return []byte{major<<5 | 25, byte(arg >> 8), byte(arg)}
+		default:
+			out := []byte{major<<5 | 26, 0, 0, 0, 0}
+			binary.BigEndian.PutUint32(out[1:], uint32(arg))
+			return out
+		}
+	}
+
+	switch v := value.(type) {
+	case int:
+		if v < 0 {
+			return head(1, uint64(-1-v))
+		}
+		return head(0, uint64(v))
+	case []byte:
+		return append(head(2, uint64(len(v))), v...)
+	case string:
+		return append(head(3, uint64(len(v))), v...)
+	case map[interface{}]interface{}:
+		keys := [][]byte{}
+		encoded := map[string][]byte{}
+		for key, item := range v {
+			k := encodeCBOR(key)
+			keys = append(keys, k)
+			encoded[string(k)] = encodeCBOR(item)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) < len(keys[j])
+			}
+			return string(keys[i]) < string(keys[j])
+		})
+		out := head(5, uint64(len(v)))
+		for _, k := range keys {
+			out = append(append(out, k...), encoded[string(k)]...)
+		}
+		return out
+	}
+	panic("unsupported type")
+}
+
+// authenticator is a software authenticator holding a single credential.
+type authenticator struct {
+	credentialID []byte
+	signer       crypto.Signer
+	cose         []byte
+	signCount    uint32
+	flags        byte
+}
+
+func newAuthenticator(t *testing.T, algorithm int64) *authenticator {
+	t.Helper()
+	a := &authenticator{credentialID: make([]byte, 16), flags: 0x01 | 0x04}
+	rand.Read(a.credentialID)
+
+	switch algorithm {
+	case webauthn.AlgES256:
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		a.signer = key
+		a.cose = encodeCBOR(map[interface{}]interface{}{
+			1: 2, 3: -7, -1: 1,
+			-2: key.X.FillBytes(make([]byte, 32)),
+			-3: key.Y.FillBytes(make([]byte, 32)),
+		})
+	case webauthn.AlgEdDSA:
+		public, private, _ := ed25519.GenerateKey(rand.Reader)
+		a.signer = private
+		a.cose = encodeCBOR(map[interface{}]interface{}{1: 1, 3: -8, -1: 6, -2: []byte(public)})
+	case webauthn.AlgRS256:
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("Failed to generate RSA key: %v", err)
+		}
+		a.signer = key
+		e := make([]byte, 4)
+		binary.BigEndian.PutUint32(e, uint32(key.E))
+		a.cose = encodeCBOR(map[interface{}]interface{}{1: 3, 3: -257, -1: key.N.Bytes(), -2: e[1:]})
+	}
+	return a
+}
+
+func (a *authenticator) authData(rpID string, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append([]byte{}, rpIDHash[:]...)
+	flags := a.flags
+	if attested {
+		flags |= 0x40
+	}
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if attested {
+		data = append(data, make([]byte, 16)...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, a.cose...)
+	}
+	return data
+}
+
+func clientDataJSON(ceremony, challenge, origin string) []byte {
+	data, _ := json.Marshal(map[string]interface{}{"type": ceremony, "challenge": challenge, "origin": origin})
+	return data
+}
+
+func (a *authenticator) create(challenge, rpID, origin string) webauthn.RegistrationResponse {
+	attestation := encodeCBOR(map[interface{}]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[interface{}]interface{}{},
+		"authData": a.authData(rpID, true),
+	})
+	return webauthn.RegistrationResponse{
+		ID:    b64.EncodeToString(a.credentialID),
+		RawID: b64.EncodeToString(a.credentialID),
+		Type:  "public-key",
+		Response: webauthn.AuthenticatorAttestationResponse{
+			ClientDataJSON:    b64.EncodeToString(clientDataJSON("webauthn.create", challenge, origin)),
+			AttestationObject: b64.EncodeToString(attestation),
+		},
+	}
+}
+
+func (a *authenticator) get(t *testing.T, challenge, rpID, origin string, userHandle []byte) webauthn.AssertionResponse {
+	t.Helper()
+	a.signCount++
+	authData := a.authData(rpID, false)
+	clientData := clientDataJSON("webauthn.get", challenge, origin)
+	clientDataHash := sha256.Sum256(clientData)
+	signed := append(append([]byte{}, authData...), clientDataHash[:]...)
+
+	var signature []byte
+	var err error
+	if _, ok := a.signer.(ed25519.PrivateKey); ok {
+		signature, err = a.signer.Sign(rand.Reader, signed, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(signed)
+		signature, err = a.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	return webauthn.AssertionResponse{
+		ID:    b64.EncodeToString(a.credentialID),
+		RawID: b64.EncodeToString(a.credentialID),
+		Type:  "public-key",
+		Response: webauthn.AuthenticatorAssertionResponse{
+			ClientDataJSON:    b64.EncodeToString(clientData),
+			AuthenticatorData: b64.EncodeToString(authData),
+			Signature:         b64.EncodeToString(signature),
+			UserHandle:        b64.EncodeToString(userHandle),
+		},
+	}
+}
+
+func newChallenge(t *testing.T) string {
+	t.Helper()
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		t.Fatalf("NewChallenge failed: %v", err)
+	}
+	return challenge
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	rp := webauthn.NewRelyingParty(rpID, "Chirpy", []string{origin})
+	userHandle := []byte("user-handle")
+
+	tests := []struct {
+		name      string
+		algorithm int64
+	}{
+		{name: "ES256", algorithm: webauthn.AlgES256},
+		{name: "EdDSA", algorithm: webauthn.AlgEdDSA},
+		{name: "RS256", algorithm: webauthn.AlgRS256},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := newAuthenticator(t, tc.algorithm)
+
+			challenge := newChallenge(t)
+			registration := a.create(challenge, rpID, origin)
+			got, err := registration.Challenge()
+			if err != nil || got != challenge {
+				t.Fatalf("Challenge() = %q, %v, want %q", got, err, challenge)
+			}
+			credential, err := rp.VerifyRegistration(challenge, registration)
+			if err != nil {
+				t.Fatalf("VerifyRegistration failed: %v", err)
+			}
+			if credential.Algorithm != tc.algorithm {
+				t.Errorf("Got algorithm %d, want %d", credential.Algorithm, tc.algorithm)
+			}
+
+			for i := 0; i < 2; i++ {
+				challenge = newChallenge(t)
+				assertion, err := rp.VerifyAssertion(challenge, credential, userHandle, a.get(t, challenge, rpID, origin, userHandle))
+				if err != nil {
+					t.Fatalf("VerifyAssertion failed: %v", err)
+				}
+				if assertion.SignCount != a.signCount || !assertion.UserVerified {
+					t.Errorf("Got assertion %+v, want sign count %d and user verified", assertion, a.signCount)
+				}
+				credential.SignCount = assertion.SignCount
+			}
+		})
+	}
+}
+
+func TestVerifyRegistrationRejects(t *testing.T) {
+	rp := webauthn.NewRelyingParty(rpID, "Chirpy", []string{origin})
+	challenge := newChallenge(t)
+
+	tests := []struct {
+		name     string
+		response func(a *authenticator) webauthn.RegistrationResponse
+		want     error
+	}{
+		{
+			name:     "other challenge",
+			response: func(a *authenticator) webauthn.RegistrationResponse { return a.create(newChallenge(t), rpID, origin) },
+			want:     webauthn.ErrChallenge,
+		},
+		{
+			name: "other origin",
+			response: func(a *authenticator) webauthn.RegistrationResponse {
+				return a.create(challenge, rpID, "https://evil.example")
+			},
+			want: webauthn.ErrOrigin,
+		},
+		{
+			name: "other relying party",
+			response: func(a *authenticator) webauthn.RegistrationResponse {
+				return a.create(challenge, "evil.example", origin)
+			},
+			want: webauthn.ErrRPID,
+		},
+		{
+			name: "user not verified",
+			response: func(a *authenticator) webauthn.RegistrationResponse {
+				a.flags = 0x01
+				return a.create(challenge, rpID, origin)
+			},
+			want: webauthn.ErrUser,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := rp.VerifyRegistration(challenge, tc.response(newAuthenticator(t, webauthn.AlgES256)))
+			if !errors.Is(err, tc.want) {
+				t.Errorf("VerifyRegistration returned %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertionRejects(t *testing.T) {
+	rp := webauthn.NewRelyingParty(rpID, "Chirpy", []string{origin})
+	userHandle := []byte("user-handle")
+
+	register := func(t *testing.T) (*authenticator, webauthn.Credential) {
+		a := newAuthenticator(t, webauthn.AlgES256)
+		challenge := newChallenge(t)
+		credential, err := rp.VerifyRegistration(challenge, a.create(challenge, rpID, origin))
+		if err != nil {
+			t.Fatalf("VerifyRegistration failed: %v", err)
+		}
+		return a, credential
+	}
+
+	t.Run("other challenge", func(t *testing.T) {
+		a, credential := register(t)
+		_, err := rp.VerifyAssertion(newChallenge(t), credential, userHandle, a.get(t, newChallenge(t), rpID, origin, userHandle))
+		if !errors.Is(err, webauthn.ErrChallenge) {
+			t.Errorf("VerifyAssertion returned %v, want %v", err, webauthn.ErrChallenge)
+		}
+	})
+
+	t.Run("other credential", func(t *testing.T) {
+		_, credential := register(t)
+		other, _ := register(t)
+		challenge := newChallenge(t)
+		_, err := rp.VerifyAssertion(challenge, credential, userHandle, other.get(t, challenge, rpID, origin, userHandle))
+		if err == nil {
+			t.Error("VerifyAssertion accepted another credential")
+		}
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		a, credential := register(t)
+		challenge := newChallenge(t)
+		_, err := rp.VerifyAssertion(challenge, credential, userHandle, a.get(t, challenge, rpID, origin, []byte("someone-else")))
+		if err == nil {
+			t.Error("VerifyAssertion accepted another user handle")
+		}
+	})
+
+	t.Run("forged signature", func(t *testing.T) {
+		a, credential := register(t)
+		challenge := newChallenge(t)
+		response := a.get(t, challenge, rpID, origin, userHandle)
+		response.Response.Signature = a.get(t, newChallenge(t), rpID, origin, userHandle).Response.Signature
+		_, err := rp.VerifyAssertion(challenge, credential, userHandle, response)
+		if err == nil {
+			t.Error("VerifyAssertion accepted a signature over other data")
+		}
+	})
+
+	t.Run("counter went backwards", func(t *testing.T) {
+		a, credential := register(t)
+		credential.SignCount = 10
+		challenge := newChallenge(t)
+		_, err := rp.VerifyAssertion(challenge, credential, userHandle, a.get(t, challenge, rpID, origin, userHandle))
+		if !errors.Is(err, webauthn.ErrSignCount) {
+			t.Errorf("VerifyAssertion returned %v, want %v", err, webauthn.ErrSignCount)
+		}
+	})
+}
+
+func TestRequestOptions(t *testing.T) {
+	rp := webauthn.NewRelyingParty(rpID, "Chirpy", []string{origin})
+	options := rp.RequestOptions("challenge")
+
+	data, err := json.Marshal(options)
+	if err != nil {
+		t.Fatalf("Failed to marshal options: %v", err)
+	}
+	want := `{"publicKey":{"challenge":"challenge","timeout":300000,"rpId":"chirpy.example","allowCredentials":[],"userVerification":"required"}}`
+	if string(data) != want {
+		t.Errorf("Got options %s, want %s", data, want)
+	}
+}
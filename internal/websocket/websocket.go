@@ -0,0 +1,361 @@
+// Package websocket is a small server side implementation of RFC 6455. It
+// supports what the API needs: the opening handshake, text and binary
+// messages, fragmentation, ping/pong and the closing handshake. The server
+// can select one of the subprotocols a client offers; extensions are not
+// supported.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	OpContinuation = 0x0
+	OpText         = 0x1
+	OpBinary       = 0x2
+	OpClose        = 0x8
+	OpPing         = 0x9
+	OpPong         = 0xA
+)
+
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseNoStatus        = 1005
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+	CloseTryAgainLater   = 1013
+)
+
+const (
+	DefaultMaxMessageSize = 64 * 1024
+	maxControlPayload     = 125
+	acceptGUID            = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+// CloseError is returned by ReadMessage once the peer has sent a close frame.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket closed with code %d: %s", e.Code, e.Reason)
+}
+
+// Conn is an upgraded connection. ReadMessage must only be called from one
+// goroutine at a time; writes are safe for concurrent use.
+type Conn struct {
+	MaxMessageSize int64
+
+	conn    net.Conn
+	reader  *bufio.Reader
+	writeMu sync.Mutex
+	closing bool
+}
+
+// Upgrade performs the opening handshake. Requests that are not valid
+// websocket handshakes are rejected before the connection is hijacked, so the
+// caller can still respond with a regular HTTP error.
+func Upgrade(writer http.ResponseWriter, request *http.Request) (*Conn, error) {
+	return UpgradeWithSubprotocol(writer, request, "")
+}
+
+// UpgradeWithSubprotocol is Upgrade, but also answers with protocol as the
+// selected subprotocol, which must be one the client offered. An empty
+// protocol selects none.
+func UpgradeWithSubprotocol(writer http.ResponseWriter, request *http.Request, protocol string) (*Conn, error) {
+	if request.Method != http.MethodGet {
+		return nil, fmt.Errorf("websocket handshake must use GET")
+	}
+	if !headerContainsToken(request.Header, "Connection", "upgrade") {
+		return nil, fmt.Errorf("Connection header must contain 'upgrade'")
+	}
+	if !headerContainsToken(request.Header, "Upgrade", "websocket") {
+		return nil, fmt.Errorf("Upgrade header must be 'websocket'")
+	}
+	if request.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, fmt.Errorf("Unsupported websocket version")
+	}
+	key := request.Header.Get("Sec-WebSocket-Key")
+	decodedKey, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(decodedKey) != 16 {
+		return nil, fmt.Errorf("Invalid Sec-WebSocket-Key")
+	}
+	if protocol != "" && !slices.Contains(Subprotocols(request), protocol) {
+		return nil, fmt.Errorf("Client did not offer subprotocol %s", protocol)
+	}
+
+	hijacker, ok := writer.(http.Hijacker)
+	if !ok {
+		return nil, fmt.Errorf("ResponseWriter does not implement http.Hijacker")
+	}
+	netConn, readWriter, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + AcceptKey(key) + "\r\n"
+	if protocol != "" {
+		response += "Sec-WebSocket-Protocol: " + protocol + "\r\n"
+	}
+	response += "\r\n"
+	_, err = readWriter.WriteString(response)
+	if err == nil {
+		err = readWriter.Flush()
+	}
+	if err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	return &Conn{
+		MaxMessageSize: DefaultMaxMessageSize,
+		conn:           netConn,
+		reader:         readWriter.Reader,
+	}, nil
+}
+
+// AcceptKey computes the Sec-WebSocket-Accept value for a handshake key.
+func AcceptKey(key string) string {
+	hash := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// Subprotocols returns the subprotocols the client offered in its
+// Sec-WebSocket-Protocol headers, in order of preference.
+func Subprotocols(request *http.Request) []string {
+	var protocols []string
+	for _, value := range request.Header.Values("Sec-WebSocket-Protocol") {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part != "" {
+				protocols = append(protocols, part)
+			}
+		}
+	}
+	return protocols
+}
+
+func headerContainsToken(headers http.Header, name, token string) bool {
+	for _, value := range headers.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadMessage returns the next data message or pong. Fragmented messages are
+// reassembled and pings are answered automatically. After the peer closes
+// the connection a *CloseError is returned.
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	messageOpcode := -1
+	var message []byte
+
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case OpPing:
+			err = c.writeFrame(OpPong, payload)
+			if err != nil {
+				return 0, nil, err
+			}
+			continue
+		case OpPong:
+			return OpPong, payload, nil
+		case OpClose:
+			return 0, nil, c.handleClose(payload)
+		case OpText, OpBinary:
+			if messageOpcode != -1 {
+				return 0, nil, c.fail(CloseProtocolError, "Expected continuation frame")
+			}
+			messageOpcode = opcode
+		case OpContinuation:
+			if messageOpcode == -1 {
+				return 0, nil, c.fail(CloseProtocolError, "Unexpected continuation frame")
+			}
+		default:
+			return 0, nil, c.fail(CloseProtocolError, "Unknown opcode")
+		}
+
+		if int64(len(message)+len(payload)) > c.MaxMessageSize {
+			return 0, nil, c.fail(CloseMessageTooBig, "Message is too big")
+		}
+		message = append(message, payload...)
+
+		if fin {
+			if messageOpcode == OpText && !utf8.Valid(message) {
+				return 0, nil, c.fail(CloseInvalidPayload, "Text message is not valid UTF-8")
+			}
+			return messageOpcode, message, nil
+		}
+	}
+}
+
+func (c *Conn) readFrame() (bool, int, []byte, error) {
+	header := make([]byte, 2)
+	_, err := io.ReadFull(c.reader, header)
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := header[0]&0x80 != 0
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "Reserved bits must be zero")
+	}
+	opcode := int(header[0] & 0x0F)
+	masked := header[1]&0x80 != 0
+	if !masked {
+		return false, 0, nil, c.fail(CloseProtocolError, "Client frames must be masked")
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		_, err = io.ReadFull(c.reader, extended)
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		_, err = io.ReadFull(c.reader, extended)
+		length = binary.BigEndian.Uint64(extended)
+	}
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	if opcode >= OpClose && (!fin || length > maxControlPayload) {
+		return false, 0, nil, c.fail(CloseProtocolError, "Invalid control frame")
+	}
+	if length > uint64(c.MaxMessageSize) {
+		return false, 0, nil, c.fail(CloseMessageTooBig, "Message is too big")
+	}
+
+	mask := make([]byte, 4)
+	_, err = io.ReadFull(c.reader, mask)
+	if err != nil {
+		return false, 0, nil, err
+	}
+	payload := make([]byte, length)
+	_, err = io.ReadFull(c.reader, payload)
+	if err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+func (c *Conn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatus}
+	if len(payload) >= 2 {
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Reason = string(payload[2:])
+	}
+
+	// Echo the status code to complete the closing handshake.
+	code := closeErr.Code
+	if code == CloseNoStatus {
+		code = CloseNormal
+	}
+	c.WriteClose(code, "")
+	return closeErr
+}
+
+// fail closes the connection after a protocol violation by the peer.
+func (c *Conn) fail(code int, reason string) error {
+	c.WriteClose(code, reason)
+	return &CloseError{Code: code, Reason: reason}
+}
+
+// WriteMessage sends a single unfragmented message.
+func (c *Conn) WriteMessage(opcode int, payload []byte) error {
+	if opcode >= OpClose && len(payload) > maxControlPayload {
+		return fmt.Errorf("control frame payload is too big")
+	}
+	return c.writeFrame(opcode, payload)
+}
+
+// WriteClose starts the closing handshake. Only the first call sends a frame;
+// nothing can be written after it.
+func (c *Conn) WriteClose(code int, reason string) error {
+	if len(reason) > maxControlPayload-2 {
+		reason = reason[:maxControlPayload-2]
+	}
+	payload := make([]byte, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	copy(payload[2:], reason)
+	return c.writeFrame(OpClose, payload)
+}
+
+var errClosing = errors.New("websocket is closing")
+
+func (c *Conn) writeFrame(opcode int, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closing {
+		return errClosing
+	}
+	if opcode == OpClose {
+		c.closing = true
+	}
+
+	header := []byte{0x80 | byte(opcode)}
+	length := len(payload)
+	switch {
+	case length <= 125:
+		header = append(header, byte(length))
+	case length <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	_, err := c.conn.Write(append(header, payload...))
+	return err
+}
+
+func (c *Conn) SetReadDeadline(deadline time.Time) error {
+	return c.conn.SetReadDeadline(deadline)
+}
+
+func (c *Conn) SetWriteDeadline(deadline time.Time) error {
+	return c.conn.SetWriteDeadline(deadline)
+}
+
+// Close closes the underlying connection without a closing handshake.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,268 @@
+package websocket_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kwekkwekpatu/chirpy/internal/websocket"
+)
+
+const handshakeKey = "dGhlIHNhbXBsZSBub25jZQ=="
+
+func TestAcceptKey(t *testing.T) {
+	// Example from RFC 6455 section 1.3.
+	got := websocket.AcceptKey(handshakeKey)
+	if got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Got wrong accept key: %s", got)
+	}
+}
+
+func TestUpgradeRejectsInvalidHandshake(t *testing.T) {
+	testCases := []struct {
+		name    string
+		method  string
+		headers map[string]string
+	}{
+		{
+			name:    "plain request",
+			method:  http.MethodGet,
+			headers: map[string]string{},
+		},
+		{
+			name:   "wrong method",
+			method: http.MethodPost,
+			headers: map[string]string{
+				"Connection": "Upgrade", "Upgrade": "websocket",
+				"Sec-WebSocket-Version": "13", "Sec-WebSocket-Key": handshakeKey,
+			},
+		},
+		{
+			name:   "wrong version",
+			method: http.MethodGet,
+			headers: map[string]string{
+				"Connection": "Upgrade", "Upgrade": "websocket",
+				"Sec-WebSocket-Version": "8", "Sec-WebSocket-Key": handshakeKey,
+			},
+		},
+		{
+			name:   "invalid key",
+			method: http.MethodGet,
+			headers: map[string]string{
+				"Connection": "Upgrade", "Upgrade": "websocket",
+				"Sec-WebSocket-Version": "13", "Sec-WebSocket-Key": "short",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(tc.method, "/ws", nil)
+			for name, value := range tc.headers {
+				request.Header.Set(name, value)
+			}
+			_, err := websocket.Upgrade(httptest.NewRecorder(), request)
+			if err == nil {
+				t.Error("expected error but got none")
+			}
+		})
+	}
+}
+
+func TestSubprotocols(t *testing.T) {
+	testCases := []struct {
+		name    string
+		headers []string
+		want    []string
+	}{
+		{
+			name:    "none offered",
+			headers: nil,
+			want:    nil,
+		},
+		{
+			name:    "one header",
+			headers: []string{"chirpy, chirpy.bearer.abc"},
+			want:    []string{"chirpy", "chirpy.bearer.abc"},
+		},
+		{
+			name:    "several headers",
+			headers: []string{"chirpy", " chirpy.bearer.abc ,"},
+			want:    []string{"chirpy", "chirpy.bearer.abc"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/ws", nil)
+			for _, value := range tc.headers {
+				request.Header.Add("Sec-WebSocket-Protocol", value)
+			}
+			got := websocket.Subprotocols(request)
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("Got wrong subprotocols. Want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestUpgradeRejectsSubprotocolNotOffered(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Upgrade", "websocket")
+	request.Header.Set("Sec-WebSocket-Version", "13")
+	request.Header.Set("Sec-WebSocket-Key", handshakeKey)
+	request.Header.Set("Sec-WebSocket-Protocol", "other")
+
+	_, err := websocket.UpgradeWithSubprotocol(httptest.NewRecorder(), request, "chirpy")
+	if err == nil {
+		t.Error("expected error but got none")
+	}
+}
+
+func TestEchoPingAndClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		conn, err := websocket.Upgrade(writer, request)
+		if err != nil {
+			t.Errorf("Upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		for {
+			opcode, payload, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(opcode, payload)
+		}
+	}))
+	defer server.Close()
+
+	client, reader := dial(t, server.URL)
+	defer client.Close()
+
+	// A fragmented text message is reassembled before it is echoed.
+	writeClientFrame(t, client, false, websocket.OpText, []byte("hello, "))
+	writeClientFrame(t, client, true, websocket.OpContinuation, []byte("world"))
+	opcode, payload := readServerFrame(t, reader)
+	if opcode != websocket.OpText || string(payload) != "hello, world" {
+		t.Errorf("Got wrong echo. Want text 'hello, world', got opcode %d '%s'", opcode, payload)
+	}
+
+	writeClientFrame(t, client, true, websocket.OpPing, []byte("ping"))
+	opcode, payload = readServerFrame(t, reader)
+	if opcode != websocket.OpPong || string(payload) != "ping" {
+		t.Errorf("Got wrong pong. Want pong 'ping', got opcode %d '%s'", opcode, payload)
+	}
+
+	closePayload := binary.BigEndian.AppendUint16(nil, websocket.CloseNormal)
+	writeClientFrame(t, client, true, websocket.OpClose, closePayload)
+	opcode, payload = readServerFrame(t, reader)
+	if opcode != websocket.OpClose || binary.BigEndian.Uint16(payload) != websocket.CloseNormal {
+		t.Errorf("Got wrong close frame. Want code %d, got opcode %d %v", websocket.CloseNormal, opcode, payload)
+	}
+}
+
+func TestUnmaskedFrameIsRejected(t *testing.T) {
+	result := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		conn, err := websocket.Upgrade(writer, request)
+		if err != nil {
+			result <- err
+			return
+		}
+		defer conn.Close()
+		_, _, err = conn.ReadMessage()
+		result <- err
+	}))
+	defer server.Close()
+
+	client, _ := dial(t, server.URL)
+	defer client.Close()
+
+	_, err := client.Write([]byte{0x80 | websocket.OpText, 2, 'h', 'i'})
+	if err != nil {
+		t.Fatalf("Failed to write frame: %v", err)
+	}
+
+	var closeErr *websocket.CloseError
+	err = <-result
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseProtocolError {
+		t.Errorf("Expected protocol error, got %v", err)
+	}
+}
+
+func dial(t *testing.T, serverURL string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(serverURL, "http://"))
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+
+	handshake := "GET /ws HTTP/1.1\r\n" +
+		"Host: localhost\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Key: " + handshakeKey + "\r\n\r\n"
+	_, err = conn.Write([]byte(handshake))
+	if err != nil {
+		t.Fatalf("Failed to write handshake: %v", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Failed to read handshake response: %v", err)
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Got wrong status code. Want 101, got %d", response.StatusCode)
+	}
+	if response.Header.Get("Sec-WebSocket-Accept") != websocket.AcceptKey(handshakeKey) {
+		t.Fatalf("Got wrong Sec-WebSocket-Accept: %s", response.Header.Get("Sec-WebSocket-Accept"))
+	}
+	return conn, reader
+}
+
+func writeClientFrame(t *testing.T, conn net.Conn, fin bool, opcode int, payload []byte) {
+	t.Helper()
+	first := byte(opcode)
+	if fin {
+		first |= 0x80
+	}
+	mask := []byte{0x12, 0x34, 0x56, 0x78}
+	frame := []byte{first, 0x80 | byte(len(payload))}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := conn.Write(frame)
+	if err != nil {
+		t.Fatalf("Failed to write frame: %v", err)
+	}
+}
+
+func readServerFrame(t *testing.T, reader *bufio.Reader) (int, []byte) {
+	t.Helper()
+	header := make([]byte, 2)
+	_, err := io.ReadFull(reader, header)
+	if err != nil {
+		t.Fatalf("Failed to read frame: %v", err)
+	}
+	if header[1]&0x80 != 0 {
+		t.Fatal("Server frames must not be masked")
+	}
+	payload := make([]byte, header[1]&0x7F)
+	_, err = io.ReadFull(reader, payload)
+	if err != nil {
+		t.Fatalf("Failed to read payload: %v", err)
+	}
+	return int(header[0] & 0x0F), payload
+}
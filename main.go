@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/kwekkwekpatu/chirpy/internal/authz"
 	"github.com/kwekkwekpatu/chirpy/internal/handlers"
 	_ "github.com/lib/pq"
 )
 
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	mux := http.NewServeMux()
 	apiCfg := handlers.APIConfig
@@ -18,25 +27,121 @@ func main() {
 
 	mux.Handle("GET /app/*", http.StripPrefix("/app", apiCfg.MiddlewareMetricsInc(http.FileServer(http.Dir(".")))))
 	mux.HandleFunc("GET /api/healthz", handlers.ReadinessHandler)
-	mux.HandleFunc("GET /admin/metrics", apiCfg.MiddlewareMetricsResult)
+	mux.HandleFunc("GET /.well-known/jwks.json", apiCfg.JWKSHandler)
+	mux.HandleFunc("GET /admin/metrics", apiCfg.MiddlewareRequirePermission(authz.ReadMetrics, apiCfg.MiddlewareMetricsResult))
 	mux.HandleFunc("GET /api/chirps", apiCfg.ChirpReadHandler)
+	mux.HandleFunc("GET /api/chirps/search", apiCfg.ChirpSearchHandler)
+	mux.HandleFunc("GET /api/chirps/stream", apiCfg.ChirpStreamHandler)
 	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.ChirpSpecificReadHandler)
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", apiCfg.ChirpUpdateHandler)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/revisions", apiCfg.ChirpRevisionsReadHandler)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/replies", apiCfg.ChirpRepliesReadHandler)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", apiCfg.ChirpThreadReadHandler)
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.ChirpDeleteSpecificHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/restore", apiCfg.ChirpRestoreHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/report", apiCfg.ChirpReportHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/like", apiCfg.LikeHandler)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/like", apiCfg.UnlikeHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/bookmark", apiCfg.BookmarkHandler)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/bookmark", apiCfg.UnbookmarkHandler)
+	mux.HandleFunc("GET /api/bookmarks", apiCfg.BookmarksReadHandler)
 	mux.HandleFunc("POST /api/reset", apiCfg.MiddlewareMetricsReset)
 	mux.HandleFunc("POST /api/chirps", apiCfg.ChirpHandler)
+	mux.HandleFunc("POST /api/media", apiCfg.MediaUploadHandler)
+	mux.HandleFunc("GET /api/media/{mediaID}", apiCfg.MediaReadHandler)
+	mux.HandleFunc("GET /api/media/{mediaID}/thumbnail", apiCfg.MediaThumbnailReadHandler)
+	mux.HandleFunc("POST /api/drafts", apiCfg.DraftHandler)
+	mux.HandleFunc("GET /api/drafts", apiCfg.DraftsReadHandler)
+	mux.HandleFunc("GET /api/drafts/{draftID}", apiCfg.DraftSpecificReadHandler)
+	mux.HandleFunc("PUT /api/drafts/{draftID}", apiCfg.DraftUpdateHandler)
+	mux.HandleFunc("DELETE /api/drafts/{draftID}", apiCfg.DraftDeleteHandler)
+	mux.HandleFunc("POST /api/drafts/{draftID}/publish", apiCfg.DraftPublishHandler)
+	mux.HandleFunc("GET /api/scheduled-chirps", apiCfg.ScheduledChirpsReadHandler)
+	mux.HandleFunc("PUT /api/scheduled-chirps/{scheduledChirpID}", apiCfg.ScheduledChirpRescheduleHandler)
+	mux.HandleFunc("DELETE /api/scheduled-chirps/{scheduledChirpID}", apiCfg.ScheduledChirpCancelHandler)
 	mux.HandleFunc("POST /api/users", apiCfg.UserHandler)
 	mux.HandleFunc("POST /api/login", apiCfg.LoginHandler)
+	mux.HandleFunc("POST /api/login/mfa", apiCfg.LoginMFAHandler)
+	mux.HandleFunc("POST /api/login/passkey/begin", apiCfg.PasskeyLoginBeginHandler)
+	mux.HandleFunc("POST /api/login/passkey/finish", apiCfg.PasskeyLoginFinishHandler)
 	mux.HandleFunc("POST /api/refresh", apiCfg.RefreshHandler)
 	mux.HandleFunc("POST /api/revoke", apiCfg.RevokeHandler)
-	mux.HandleFunc("POST /admin/reset", apiCfg.AdminReset)
+	mux.HandleFunc("POST /admin/reset", apiCfg.MiddlewareRequirePermission(authz.ResetData, apiCfg.AdminReset))
+	mux.HandleFunc("GET /admin/moderation/rules", apiCfg.MiddlewareRequirePermission(authz.ManageModerationRules, apiCfg.ModerationRulesReadHandler))
+	mux.HandleFunc("POST /admin/moderation/rules", apiCfg.MiddlewareRequirePermission(authz.ManageModerationRules, apiCfg.ModerationRuleHandler))
+	mux.HandleFunc("DELETE /admin/moderation/rules/{ruleID}", apiCfg.MiddlewareRequirePermission(authz.ManageModerationRules, apiCfg.ModerationRuleDeleteHandler))
+	mux.HandleFunc("POST /admin/moderation/reload", apiCfg.MiddlewareRequirePermission(authz.ManageModerationRules, apiCfg.ModerationReloadHandler))
+	mux.HandleFunc("GET /admin/moderation/actions", apiCfg.MiddlewareRequirePermission(authz.ReadModerationActions, apiCfg.ModerationActionsReadHandler))
+	mux.HandleFunc("GET /admin/reports", apiCfg.MiddlewareRequirePermission(authz.ReadReports, apiCfg.ReportsReadHandler))
+	mux.HandleFunc("GET /admin/roles", apiCfg.MiddlewareRequirePermission(authz.ManageRoles, apiCfg.RolesReadHandler))
+	mux.HandleFunc("GET /admin/users/{userID}/roles", apiCfg.MiddlewareRequirePermission(authz.ManageRoles, apiCfg.UserRolesReadHandler))
+	mux.HandleFunc("PUT /admin/users/{userID}/roles/{role}", apiCfg.MiddlewareRequirePermission(authz.ManageRoles, apiCfg.RoleGrantHandler))
+	mux.HandleFunc("DELETE /admin/users/{userID}/roles/{role}", apiCfg.MiddlewareRequirePermission(authz.ManageRoles, apiCfg.RoleRevokeHandler))
+	mux.HandleFunc("POST /admin/reports/{reportID}/resolve", apiCfg.MiddlewareRequirePermission(authz.ResolveReports, apiCfg.ReportResolveHandler))
 	mux.HandleFunc("PUT /api/users", apiCfg.UpdateUserPasswordHandler)
+	mux.HandleFunc("GET /api/mfa", apiCfg.MFAStatusHandler)
+	mux.HandleFunc("POST /api/mfa/totp", apiCfg.TOTPEnrollHandler)
+	mux.HandleFunc("POST /api/mfa/totp/confirm", apiCfg.TOTPConfirmHandler)
+	mux.HandleFunc("DELETE /api/mfa/totp", apiCfg.TOTPDisableHandler)
+	mux.HandleFunc("POST /api/mfa/recovery-codes", apiCfg.RecoveryCodesHandler)
+	mux.HandleFunc("GET /api/passkeys", apiCfg.PasskeysReadHandler)
+	mux.HandleFunc("POST /api/passkeys/register/begin", apiCfg.PasskeyRegisterBeginHandler)
+	mux.HandleFunc("POST /api/passkeys/register/finish", apiCfg.PasskeyRegisterFinishHandler)
+	mux.HandleFunc("DELETE /api/passkeys/{passkeyID}", apiCfg.PasskeyDeleteHandler)
+	mux.HandleFunc("GET /api/sessions", apiCfg.SessionsReadHandler)
+	mux.HandleFunc("DELETE /api/sessions/{sessionID}", apiCfg.SessionRevokeHandler)
+	mux.HandleFunc("POST /api/sessions/revoke-all", apiCfg.SessionsRevokeAllHandler)
+	mux.HandleFunc("POST /api/users/{userID}/follow", apiCfg.FollowHandler)
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", apiCfg.UnfollowHandler)
+	mux.HandleFunc("GET /api/users/{userID}/likes", apiCfg.UserLikesReadHandler)
+	mux.HandleFunc("POST /api/users/{userID}/report", apiCfg.UserReportHandler)
+	mux.HandleFunc("GET /api/timeline", apiCfg.TimelineHandler)
+	mux.HandleFunc("GET /api/ws", apiCfg.WebSocketHandler)
+	mux.HandleFunc("GET /api/notifications", apiCfg.NotificationsReadHandler)
+	mux.HandleFunc("POST /api/notifications/read", apiCfg.NotificationsMarkReadHandler)
+	mux.HandleFunc("GET /api/hashtags/trending", apiCfg.TrendingHashtagsHandler)
+	mux.HandleFunc("GET /api/hashtags/{tag}/chirps", apiCfg.HashtagChirpsReadHandler)
 	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.UpgradeUser)
 
 	mux.HandleFunc("GET /", handlers.DockerHandler)
 
+	// Background workers stop when the server is asked to shut down.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	workers := sync.WaitGroup{}
+	if apiCfg != nil {
+		for _, run := range []func(context.Context){
+			apiCfg.RunScheduledChirpPublisher,
+			apiCfg.RunDeletedChirpPurger,
+			apiCfg.RunModerationRuleReloader,
+		} {
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				run(ctx)
+			}()
+		}
+	}
+
 	server := &http.Server{
 		Addr:    port,
 		Handler: mux,
 	}
-	fmt.Println(server.ListenAndServe())
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		err := server.Shutdown(shutdownCtx)
+		if err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	err := server.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		fmt.Println(err)
+	}
+	stop()
+	workers.Wait()
 }
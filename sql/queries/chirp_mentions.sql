@@ -0,0 +1,22 @@
+-- name: CreateChirpMentions :many
+INSERT INTO chirp_mentions (chirp_id, user_id, created_at)
+SELECT sqlc.arg('chirp_id')::uuid, users.id, NOW()
+FROM users
+WHERE users.username = ANY(sqlc.arg('usernames')::text[])
+ON CONFLICT DO NOTHING
+RETURNING user_id;
+
+-- name: DeleteStaleChirpMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_mentions.chirp_id = sqlc.arg('chirp_id')::uuid
+AND chirp_mentions.user_id NOT IN (
+    SELECT users.id FROM users
+    WHERE users.username = ANY(sqlc.arg('usernames')::text[])
+);
+
+-- name: ReadChirpMentions :many
+SELECT chirp_mentions.chirp_id, users.id AS user_id, users.username
+FROM chirp_mentions
+JOIN users ON users.id = chirp_mentions.user_id
+WHERE chirp_mentions.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_mentions.created_at, users.username;
@@ -1,21 +1,142 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES ( gen_random_uuid(), NOW(), NOW(), $1, $2)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, parent_id, quoted_chirp_id)
+VALUES ( gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4)
 RETURNING *;
 
 -- name: DeleteChirp :exec
 DELETE FROM chirps;
 
 -- name: ReadChirp :one
-SELECT id, created_at, updated_at, body, user_id
-FROM chirps
+SELECT * FROM chirps
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: ReadChirpIncludingDeleted :one
+SELECT * FROM chirps
 WHERE id = $1;
 
+-- name: ReadChirpsByIDs :many
+SELECT * FROM chirps
+WHERE id = ANY(sqlc.arg('ids')::uuid[])
+AND deleted_at IS NULL;
+
 -- name: ReadAllChirps :many
-SELECT id, created_at, updated_at, body, user_id
-FROM chirps
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
 ORDER BY created_at;
 
--- name: DeleteSpecificChirp :exec
+-- name: SoftDeleteChirp :execrows
+UPDATE chirps
+SET deleted_at = $3
+WHERE user_id = $1 AND id = $2 AND deleted_at IS NULL;
+
+-- name: HideChirp :one
+UPDATE chirps
+SET deleted_at = COALESCE(deleted_at, $2), hidden_at = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at = NULL, hidden_at = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: ReadPurgeableChirpIDs :many
+SELECT id FROM chirps
+WHERE deleted_at < sqlc.arg('deleted_before')::timestamp
+AND NOT EXISTS (
+    SELECT 1 FROM reports
+    WHERE reports.chirp_id = chirps.id AND reports.resolved_at IS NULL
+)
+ORDER BY deleted_at
+LIMIT sqlc.arg('page_limit');
+
+-- name: PurgeChirps :exec
 DELETE FROM chirps
-WHERE user_id = $1 AND id = $2;
+WHERE id = ANY(sqlc.arg('ids')::uuid[]) AND deleted_at IS NOT NULL;
+
+-- name: ReadChirpsAsc :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_limit');
+
+-- name: ReadChirpsDesc :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: SearchChirps :many
+SELECT sqlc.embed(chirps), ts_rank(search_vector, websearch_to_tsquery('english', sqlc.arg('query'))) AS rank
+FROM chirps
+WHERE search_vector @@ websearch_to_tsquery('english', sqlc.arg('query'))
+AND deleted_at IS NULL
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+ORDER BY rank DESC, created_at DESC, id DESC
+LIMIT sqlc.arg('page_limit') OFFSET sqlc.arg('page_offset');
+
+-- name: ReadChirpForUpdate :one
+SELECT * FROM chirps
+WHERE id = $1 AND deleted_at IS NULL
+FOR UPDATE;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET updated_at = NOW(), body = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: ReadChirpReplies :many
+SELECT * FROM chirps
+WHERE parent_id = sqlc.arg('parent_id')::uuid
+AND deleted_at IS NULL
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_limit');
+
+-- name: ReadChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT c.id, c.parent_id, 0 AS depth
+    FROM chirps c
+    WHERE c.id = sqlc.arg('chirp_id')::uuid
+    UNION ALL
+    SELECT c.id, c.parent_id, a.depth + 1
+    FROM chirps c
+    JOIN ancestors a ON c.id = a.parent_id
+)
+SELECT chirps.* FROM chirps
+JOIN ancestors ON chirps.id = ancestors.id
+WHERE ancestors.depth > 0
+AND chirps.deleted_at IS NULL
+ORDER BY ancestors.depth DESC;
+
+-- name: ReadChirpDescendants :many
+WITH RECURSIVE descendants AS (
+    SELECT c.id
+    FROM chirps c
+    WHERE c.parent_id = sqlc.arg('root_id')::uuid
+    UNION ALL
+    SELECT c.id
+    FROM chirps c
+    JOIN descendants d ON c.parent_id = d.id
+)
+SELECT chirps.* FROM chirps
+JOIN descendants ON chirps.id = descendants.id
+WHERE chirps.deleted_at IS NULL
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('page_limit');
+
+-- name: CreateChirpWithID :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, parent_id, quoted_chirp_id)
+VALUES ( $1, NOW(), NOW(), $2, $3, $4, $5)
+RETURNING *;
@@ -0,0 +1,39 @@
+-- name: TagChirp :exec
+WITH tags AS (
+    INSERT INTO hashtags (id, created_at, tag)
+    SELECT gen_random_uuid(), NOW(), unnest(sqlc.arg('tags')::text[])
+    ON CONFLICT (tag) DO UPDATE SET tag = EXCLUDED.tag
+    RETURNING id
+)
+INSERT INTO chirp_hashtags (chirp_id, hashtag_id, created_at)
+SELECT sqlc.arg('chirp_id')::uuid, tags.id, sqlc.arg('chirp_created_at')::timestamp
+FROM tags
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteChirpHashtags :exec
+DELETE FROM chirp_hashtags
+WHERE chirp_id = $1;
+
+-- name: ReadHashtagChirps :many
+SELECT chirps.* FROM chirps
+JOIN chirp_hashtags ON chirps.id = chirp_hashtags.chirp_id
+JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+WHERE hashtags.tag = sqlc.arg('tag')
+AND chirps.deleted_at IS NULL
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: ReadTrendingHashtags :many
+SELECT hashtags.tag,
+    COUNT(*) AS usage_count,
+    SUM(EXP(-LN(2) * EXTRACT(EPOCH FROM (NOW()::timestamp - chirp_hashtags.created_at)) / sqlc.arg('half_life_seconds')::float8))::float8 AS score
+FROM chirp_hashtags
+JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+JOIN chirps ON chirps.id = chirp_hashtags.chirp_id
+WHERE chirps.deleted_at IS NULL
+AND chirp_hashtags.created_at > NOW()::timestamp - make_interval(secs => sqlc.arg('window_seconds')::float8)
+GROUP BY hashtags.tag
+ORDER BY score DESC, hashtags.tag
+LIMIT sqlc.arg('page_limit');
@@ -0,0 +1,39 @@
+-- name: CreateMedia :one
+INSERT INTO media (id, created_at, user_id, content_type, width, height, storage_key, thumbnail_key, thumbnail_content_type)
+VALUES ( $1, NOW(), $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: ReadMedia :one
+SELECT * FROM media
+WHERE id = $1;
+
+-- name: AttachMedia :execrows
+UPDATE media
+SET chirp_id = sqlc.arg('chirp_id'), position = array_position(sqlc.arg('ids')::uuid[], id)
+WHERE id = ANY(sqlc.arg('ids')::uuid[])
+AND user_id = sqlc.arg('user_id')
+AND chirp_id IS NULL;
+
+-- name: ReadChirpMedia :many
+SELECT * FROM media
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_id, position;
+
+-- name: DeleteStaleMedia :many
+DELETE FROM media
+WHERE id IN (
+    SELECT media.id FROM media
+    WHERE media.chirp_id IS NULL
+    AND media.created_at < NOW()::timestamp - make_interval(secs => sqlc.arg('max_age_seconds')::float8)
+    AND NOT EXISTS (
+        SELECT 1 FROM scheduled_chirps
+        WHERE media.id = ANY(scheduled_chirps.media_ids)
+    )
+    ORDER BY media.created_at
+    LIMIT sqlc.arg('page_limit')
+)
+RETURNING *;
+
+-- name: DeleteAllMedia :many
+DELETE FROM media
+RETURNING *;
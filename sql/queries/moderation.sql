@@ -0,0 +1,18 @@
+-- name: CreateModerationRule :one
+INSERT INTO moderation_rules (id, created_at, kind, action, pattern)
+VALUES ( gen_random_uuid(), NOW(), $1, $2, $3)
+RETURNING *;
+
+-- name: ReadModerationRules :many
+SELECT * FROM moderation_rules
+ORDER BY created_at, id;
+
+-- name: DeleteModerationRule :one
+DELETE FROM moderation_rules
+WHERE id = $1
+RETURNING *;
+
+-- name: CreateAutomatedReport :exec
+INSERT INTO reports (id, created_at, chirp_id, user_id, reason, details)
+VALUES ( gen_random_uuid(), NOW(), $1, $2, 'automated', $3)
+ON CONFLICT (chirp_id) WHERE reason = 'automated' AND resolved_at IS NULL DO NOTHING;
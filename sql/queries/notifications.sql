@@ -0,0 +1,29 @@
+-- name: CreateNotification :exec
+INSERT INTO notifications (id, created_at, user_id, actor_id, type, chirp_id)
+VALUES ( gen_random_uuid(), NOW(), $1, $2, $3, $4);
+
+-- name: NotificationExists :one
+SELECT EXISTS (
+    SELECT 1 FROM notifications
+    WHERE user_id = $1 AND actor_id = $2 AND type = $3 AND chirp_id = $4
+);
+
+-- name: ReadNotifications :many
+SELECT * FROM notifications
+WHERE user_id = sqlc.arg('user_id')
+AND (NOT sqlc.arg('unread_only')::boolean OR read_at IS NULL)
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: MarkNotificationsRead :execrows
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = sqlc.arg('user_id')
+AND read_at IS NULL
+AND (sqlc.arg('mark_all')::boolean OR id = ANY(sqlc.arg('ids')::uuid[]));
@@ -0,0 +1,40 @@
+-- name: CreatePasskey :one
+INSERT INTO passkeys (id, user_id, credential_id, name, public_key, algorithm, sign_count, backup_eligible, backed_up, transports, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+RETURNING *;
+
+-- name: ReadUserPasskeys :many
+SELECT * FROM passkeys
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: ReadPasskeyByCredentialIDForUpdate :one
+SELECT * FROM passkeys
+WHERE credential_id = $1
+FOR UPDATE;
+
+-- name: UsePasskey :exec
+UPDATE passkeys
+SET sign_count = $2, backed_up = $3, last_used_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteUserPasskey :execrows
+DELETE FROM passkeys
+WHERE id = $1 AND user_id = $2;
+
+-- name: DeletePasskeysByUser :exec
+DELETE FROM passkeys
+WHERE user_id = $1;
+
+-- name: CreateWebAuthnChallenge :exec
+INSERT INTO webauthn_challenges (challenge, ceremony, user_id, created_at, expires_at)
+VALUES ($1, $2, $3, NOW(), $4);
+
+-- name: TakeWebAuthnChallenge :one
+DELETE FROM webauthn_challenges
+WHERE challenge = $1 AND ceremony = $2
+RETURNING *;
+
+-- name: DeleteExpiredWebAuthnChallenges :exec
+DELETE FROM webauthn_challenges
+WHERE expires_at < $1;
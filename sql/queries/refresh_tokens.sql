@@ -1,6 +1,6 @@
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, expires_at, user_id)
-VALUES ( $1, NOW(), NOW(), $2, $3)
+INSERT INTO refresh_tokens (token, created_at, updated_at, expires_at, user_id, family_id, user_agent, ip, last_used_at)
+VALUES ( $1, NOW(), NOW(), $2, $3, $4, $5, $6, NOW())
 RETURNING *;
 
 -- name: DeleteRefreshTokens :exec
@@ -11,6 +11,11 @@ SELECT token, user_id, created_at, updated_at, expires_at, revoked_at
 FROM refresh_tokens
 WHERE token = $1;
 
+-- name: ReadRefreshTokenForUpdate :one
+SELECT * FROM refresh_tokens
+WHERE token = $1
+FOR UPDATE;
+
 -- name: GetUserFromRefreshToken :one
 SELECT users.id, users.email
 FROM users
@@ -22,3 +27,41 @@ WHERE refresh_tokens.token = $1;
 UPDATE refresh_tokens
 SET revoked_at = NOW()
 WHERE token = $1;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeOtherUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE user_id = $1 AND family_id IS DISTINCT FROM $2 AND revoked_at IS NULL;
+
+-- name: RevokeUserSession :execrows
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL;
+
+-- name: ReadUserSessions :many
+SELECT
+    refresh_tokens.family_id,
+    refresh_tokens.user_agent,
+    refresh_tokens.ip,
+    refresh_tokens.last_used_at,
+    refresh_tokens.expires_at,
+    (
+        SELECT MIN(family.created_at)
+        FROM refresh_tokens AS family
+        WHERE family.family_id = refresh_tokens.family_id
+    )::TIMESTAMP AS started_at
+FROM refresh_tokens
+WHERE refresh_tokens.user_id = sqlc.arg('user_id')
+AND refresh_tokens.revoked_at IS NULL
+AND refresh_tokens.expires_at > sqlc.arg('now')::timestamp
+ORDER BY refresh_tokens.last_used_at DESC;
@@ -0,0 +1,42 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, reporter_id, chirp_id, user_id, reason, details)
+VALUES ( gen_random_uuid(), NOW(), $1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: ReadOpenReports :many
+SELECT * FROM reports
+WHERE resolved_at IS NULL
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_limit');
+
+-- name: ReadResolvedReports :many
+SELECT * FROM reports
+WHERE resolved_at IS NOT NULL
+AND (sqlc.narg('cursor_resolved_at')::timestamp IS NULL
+    OR (resolved_at, id) < (sqlc.narg('cursor_resolved_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY resolved_at DESC, id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: ReadReportForUpdate :one
+SELECT * FROM reports
+WHERE id = $1
+FOR UPDATE;
+
+-- name: ResolveReport :one
+UPDATE reports
+SET resolved_at = NOW(), resolved_by = $2, resolution = $3, resolution_note = $4
+WHERE id = $1
+RETURNING *;
+
+-- name: CreateModerationAction :exec
+INSERT INTO moderation_actions (id, created_at, moderator_id, action, report_id, chirp_id, user_id, note)
+VALUES ( gen_random_uuid(), NOW(), $1, $2, $3, $4, $5, $6);
+
+-- name: ReadModerationActions :many
+SELECT * FROM moderation_actions
+WHERE (sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_limit');
@@ -0,0 +1,43 @@
+-- name: ReadUserPermissions :many
+SELECT DISTINCT role_permissions.permission
+FROM user_roles
+JOIN role_permissions ON role_permissions.role_id = user_roles.role_id
+JOIN users ON users.id = user_roles.user_id
+WHERE user_roles.user_id = $1 AND users.suspended_at IS NULL;
+
+-- name: ReadRoles :many
+SELECT * FROM roles
+ORDER BY name;
+
+-- name: ReadRole :one
+SELECT * FROM roles
+WHERE name = $1;
+
+-- name: ReadRolePermissions :many
+SELECT roles.name, role_permissions.permission
+FROM roles
+JOIN role_permissions ON role_permissions.role_id = roles.id
+ORDER BY roles.name, role_permissions.permission;
+
+-- name: ReadUserRoles :many
+SELECT roles.name
+FROM user_roles
+JOIN roles ON roles.id = user_roles.role_id
+WHERE user_roles.user_id = $1
+ORDER BY roles.name;
+
+-- name: GrantRole :exec
+INSERT INTO user_roles (user_id, role_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: RevokeRole :execrows
+DELETE FROM user_roles
+WHERE user_id = $1 AND role_id = $2;
+
+-- name: GrantRoleByEmail :execrows
+INSERT INTO user_roles (user_id, role_id, created_at)
+SELECT users.id, roles.id, NOW()
+FROM users, roles
+WHERE users.email = sqlc.arg('email') AND roles.name = sqlc.arg('role_name')
+ON CONFLICT DO NOTHING;
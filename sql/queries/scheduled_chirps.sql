@@ -0,0 +1,38 @@
+-- name: CreateScheduledChirp :one
+INSERT INTO scheduled_chirps (id, created_at, updated_at, user_id, body, parent_id, quoted_chirp_id, media_ids, publish_at)
+VALUES ( gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: ReadScheduledChirps :many
+SELECT * FROM scheduled_chirps
+WHERE user_id = sqlc.arg('user_id')
+AND (sqlc.narg('cursor_publish_at')::timestamp IS NULL
+    OR (publish_at, id) > (sqlc.narg('cursor_publish_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY publish_at, id
+LIMIT sqlc.arg('page_limit');
+
+-- name: RescheduleChirp :one
+UPDATE scheduled_chirps
+SET publish_at = $3, updated_at = NOW(), failed_at = NULL, failure = NULL
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteScheduledChirp :execrows
+DELETE FROM scheduled_chirps
+WHERE id = $1 AND user_id = $2;
+
+-- name: ClaimDueScheduledChirp :one
+SELECT * FROM scheduled_chirps
+WHERE publish_at <= sqlc.arg('now')::timestamp AND failed_at IS NULL
+ORDER BY publish_at
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: DeletePublishedScheduledChirp :exec
+DELETE FROM scheduled_chirps
+WHERE id = $1;
+
+-- name: MarkScheduledChirpFailed :exec
+UPDATE scheduled_chirps
+SET failed_at = NOW(), failure = $2
+WHERE id = $1;
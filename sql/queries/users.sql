@@ -1,6 +1,6 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
-VALUES ( gen_random_uuid(), NOW(), NOW(), $1, $2)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, username)
+VALUES ( gen_random_uuid(), NOW(), NOW(), $1, $2, $3)
 RETURNING *;
 
 -- name: DeleteUsers :exec
@@ -14,7 +14,8 @@ SELECT * FROM users WHERE id = $1;
 
 -- name: PutPasswordByUser :one
 UPDATE users
-SET updated_at = NOW(), email = $2, hashed_password = $3
+SET updated_at = NOW(), email = $2, hashed_password = $3,
+    username = COALESCE($4, username)
 WHERE id = $1
 RETURNING *;
 
@@ -22,3 +23,9 @@ RETURNING *;
 UPDATE users
 SET updated_at = NOW(), is_chirpy_red = TRUE
 WHERE id = $1;
+
+-- name: SuspendUser :one
+UPDATE users
+SET updated_at = NOW(), suspended_at = NOW()
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- No foreign key on purpose: when the original is deleted the id is kept so
+-- that quotes and rechirps can render a tombstone.
+ALTER TABLE chirps ADD quoted_chirp_id UUID;
+CREATE INDEX chirps_quoted_chirp_id_idx ON chirps (quoted_chirp_id);
+CREATE UNIQUE INDEX chirps_rechirp_unique_idx ON chirps (user_id, quoted_chirp_id) WHERE body = '';
+
+-- +goose Down
+DROP INDEX chirps_rechirp_unique_idx;
+DROP INDEX chirps_quoted_chirp_id_idx;
+ALTER TABLE chirps
+DROP quoted_chirp_id;
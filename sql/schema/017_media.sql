@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE media (
+    id UUID PRIMARY KEY NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    chirp_id UUID REFERENCES chirps (id) ON DELETE CASCADE,
+    position INTEGER,
+    content_type TEXT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    storage_key TEXT NOT NULL,
+    thumbnail_key TEXT NOT NULL,
+    thumbnail_content_type TEXT NOT NULL
+);
+CREATE INDEX media_chirp_id_position_idx ON media (chirp_id, position);
+
+-- +goose Down
+DROP TABLE media;
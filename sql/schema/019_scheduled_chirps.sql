@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE scheduled_chirps (
+    id UUID PRIMARY KEY NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    parent_id UUID,
+    quoted_chirp_id UUID,
+    media_ids UUID[] NOT NULL,
+    publish_at TIMESTAMP NOT NULL,
+    failed_at TIMESTAMP,
+    failure TEXT
+);
+CREATE INDEX scheduled_chirps_due_idx ON scheduled_chirps (publish_at) WHERE failed_at IS NULL;
+CREATE INDEX scheduled_chirps_user_id_publish_at_idx ON scheduled_chirps (user_id, publish_at, id);
+
+-- +goose Down
+DROP TABLE scheduled_chirps;
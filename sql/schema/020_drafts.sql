@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE drafts (
+    id UUID PRIMARY KEY NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    parent_id UUID,
+    quoted_chirp_id UUID
+);
+CREATE INDEX drafts_user_id_updated_at_idx ON drafts (user_id, updated_at, id);
+
+-- +goose Down
+DROP TABLE drafts;
@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE chirps ADD deleted_at TIMESTAMP;
+CREATE INDEX chirps_deleted_at_idx ON chirps (deleted_at) WHERE deleted_at IS NOT NULL;
+-- A deleted rechirp must not keep the user from rechirping again.
+DROP INDEX chirps_rechirp_unique_idx;
+CREATE UNIQUE INDEX chirps_rechirp_unique_idx ON chirps (user_id, quoted_chirp_id) WHERE body = '' AND deleted_at IS NULL;
+
+ALTER TABLE users ADD is_moderator BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE users
+DROP is_moderator;
+
+DELETE FROM chirps WHERE deleted_at IS NOT NULL;
+DROP INDEX chirps_rechirp_unique_idx;
+CREATE UNIQUE INDEX chirps_rechirp_unique_idx ON chirps (user_id, quoted_chirp_id) WHERE body = '';
+DROP INDEX chirps_deleted_at_idx;
+ALTER TABLE chirps
+DROP deleted_at;
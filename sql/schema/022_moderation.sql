@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE moderation_rules (
+    id UUID PRIMARY KEY NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    kind TEXT NOT NULL,
+    action TEXT NOT NULL,
+    pattern TEXT NOT NULL,
+    UNIQUE (kind, pattern)
+);
+
+-- Chirps flagged by the moderation rules are queued for review as reports
+-- without a reporter.
+CREATE TABLE reports (
+    id UUID PRIMARY KEY NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    reporter_id UUID REFERENCES users (id) ON DELETE SET NULL,
+    chirp_id UUID REFERENCES chirps (id) ON DELETE SET NULL,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    details TEXT NOT NULL
+);
+CREATE INDEX reports_chirp_id_idx ON reports (chirp_id);
+-- A chirp that keeps matching the rules is only queued once.
+CREATE UNIQUE INDEX reports_automated_chirp_unique_idx ON reports (chirp_id)
+    WHERE reason = 'automated';
+
+-- +goose Down
+DROP TABLE reports;
+DROP TABLE moderation_rules;
@@ -0,0 +1,59 @@
+-- +goose Up
+ALTER TABLE reports
+ADD resolved_at TIMESTAMP,
+ADD resolved_by UUID REFERENCES users (id) ON DELETE SET NULL,
+ADD resolution TEXT,
+ADD resolution_note TEXT;
+CREATE INDEX reports_open_created_at_idx ON reports (created_at, id) WHERE resolved_at IS NULL;
+CREATE INDEX reports_resolved_at_idx ON reports (resolved_at, id) WHERE resolved_at IS NOT NULL;
+CREATE UNIQUE INDEX reports_open_chirp_unique_idx ON reports (reporter_id, chirp_id)
+    WHERE resolved_at IS NULL AND chirp_id IS NOT NULL;
+CREATE UNIQUE INDEX reports_open_user_unique_idx ON reports (reporter_id, user_id)
+    WHERE resolved_at IS NULL AND chirp_id IS NULL;
+-- Once a flag is resolved, a later edit that matches again is queued anew.
+DROP INDEX reports_automated_chirp_unique_idx;
+CREATE UNIQUE INDEX reports_open_automated_chirp_unique_idx ON reports (chirp_id)
+    WHERE reason = 'automated' AND resolved_at IS NULL;
+
+CREATE TABLE moderation_actions (
+    id UUID PRIMARY KEY NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    moderator_id UUID REFERENCES users (id) ON DELETE SET NULL,
+    action TEXT NOT NULL,
+    -- No foreign keys on the targets: the trail has to outlive them.
+    report_id UUID,
+    chirp_id UUID,
+    user_id UUID,
+    note TEXT NOT NULL
+);
+CREATE INDEX moderation_actions_created_at_idx ON moderation_actions (created_at, id);
+
+-- Hidden chirps are deleted by a moderator, their owner cannot restore them.
+ALTER TABLE chirps ADD hidden_at TIMESTAMP;
+ALTER TABLE users ADD suspended_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users
+DROP suspended_at;
+ALTER TABLE chirps
+DROP hidden_at;
+
+DROP TABLE moderation_actions;
+
+-- Only reports raised by the moderation rules existed before, one per chirp.
+DELETE FROM reports
+WHERE reporter_id IS NOT NULL;
+DELETE FROM reports
+WHERE resolved_at IS NOT NULL AND reason = 'automated';
+DROP INDEX reports_open_automated_chirp_unique_idx;
+CREATE UNIQUE INDEX reports_automated_chirp_unique_idx ON reports (chirp_id)
+    WHERE reason = 'automated';
+DROP INDEX reports_open_user_unique_idx;
+DROP INDEX reports_open_chirp_unique_idx;
+DROP INDEX reports_resolved_at_idx;
+DROP INDEX reports_open_created_at_idx;
+ALTER TABLE reports
+DROP resolution_note,
+DROP resolution,
+DROP resolved_by,
+DROP resolved_at;
@@ -0,0 +1,61 @@
+-- +goose Up
+CREATE TABLE roles (
+    id UUID PRIMARY KEY NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE role_permissions (
+    role_id UUID NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    permission TEXT NOT NULL,
+    PRIMARY KEY (role_id, permission)
+);
+
+CREATE TABLE user_roles (
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role_id UUID NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, role_id)
+);
+CREATE INDEX user_roles_role_id_idx ON user_roles (role_id);
+
+INSERT INTO roles (id, created_at, name)
+VALUES (gen_random_uuid(), NOW(), 'admin'), (gen_random_uuid(), NOW(), 'moderator');
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT roles.id, '*' FROM roles WHERE roles.name = 'admin';
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT roles.id, permission
+FROM roles, unnest(ARRAY[
+    'chirps:delete:any',
+    'chirps:read:deleted',
+    'chirps:restore:any',
+    'chirps:hide',
+    'users:suspend',
+    'reports:read',
+    'reports:resolve',
+    'moderation:actions:read'
+]) AS permission
+WHERE roles.name = 'moderator';
+
+INSERT INTO user_roles (user_id, role_id, created_at)
+SELECT users.id, roles.id, NOW()
+FROM users, roles
+WHERE users.is_moderator AND roles.name = 'moderator';
+
+ALTER TABLE users
+DROP is_moderator;
+
+-- +goose Down
+ALTER TABLE users ADD is_moderator BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE users SET is_moderator = TRUE
+WHERE id IN (
+    SELECT user_roles.user_id FROM user_roles
+    JOIN roles ON roles.id = user_roles.role_id
+    WHERE roles.name IN ('admin', 'moderator')
+);
+
+DROP TABLE user_roles;
+DROP TABLE role_permissions;
+DROP TABLE roles;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- Every login starts a family. Refreshing replaces the token with a new one
+-- in the same family, so a stolen token that is used after its replacement
+-- gives the whole family away.
+ALTER TABLE refresh_tokens ADD family_id UUID;
+UPDATE refresh_tokens SET family_id = gen_random_uuid();
+ALTER TABLE refresh_tokens ALTER family_id SET NOT NULL;
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+
+-- +goose Down
+DROP INDEX refresh_tokens_family_id_idx;
+ALTER TABLE refresh_tokens
+DROP family_id;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- A session is a refresh token family. The columns describe the device that
+-- last used it and are copied onto every rotated token.
+ALTER TABLE refresh_tokens
+ADD user_agent TEXT NOT NULL DEFAULT '',
+ADD ip TEXT NOT NULL DEFAULT '',
+ADD last_used_at TIMESTAMP;
+UPDATE refresh_tokens SET last_used_at = updated_at;
+ALTER TABLE refresh_tokens ALTER last_used_at SET NOT NULL;
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id) WHERE revoked_at IS NULL;
+
+-- +goose Down
+DROP INDEX refresh_tokens_user_id_idx;
+ALTER TABLE refresh_tokens
+DROP last_used_at,
+DROP ip,
+DROP user_agent;